@@ -0,0 +1,153 @@
+// Package admin helps rollup operators run admin-signed deposits safely.
+//
+// A batch is read from CSV (see ParseCSV), every deposit is recorded in a
+// Journal before and after it is sent, and a resumed run consults the
+// journal and the admin nonce so no deposit is credited twice. Balances
+// snapshotted before the batch let Reconcile compare each player's queried
+// state with the balance it should have after the credited deposits.
+package admin
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"math/big"
+
+	"zkwasm-minirollup-rpc-go/zkwasm"
+)
+
+var ErrPropNotSupported = errors.New("PropNotSupported")
+
+// DepositEncoder builds the transaction limbs of a deposit entry
+type DepositEncoder func(nonce *big.Int, entry *Entry) ([4]*big.Int, error)
+
+// StandardDeposit encodes the zkWasm convention deposit
+// [command, pid1, pid2, amount]. Entries carrying a prop are rejected.
+func StandardDeposit(commandID uint64) DepositEncoder {
+	return func(nonce *big.Int, entry *Entry) ([4]*big.Int, error) {
+		var limbs [4]*big.Int
+		if entry.Prop != nil && entry.Prop.Sign() != 0 {
+			return limbs, fmt.Errorf("%w: line %d", ErrPropNotSupported, entry.Line)
+		}
+		cmd, err := zkwasm.NewCommand(nonce, new(big.Int).SetUint64(commandID), big.NewInt(0))
+		if err != nil {
+			return limbs, err
+		}
+		limbs[0], err = cmd.Encode()
+		if err != nil {
+			return limbs, err
+		}
+		limbs[1], limbs[2], limbs[3] = entry.Pid1, entry.Pid2, entry.Amount
+		return limbs, nil
+	}
+}
+
+// SchemaDeposit encodes deposits through a command schema whose target
+// player parameters are named pid1 and pid2. The amount and prop columns
+// are bound to amountParam and propParam; an empty propParam rejects
+// entries carrying a prop.
+func SchemaDeposit(cs *zkwasm.CommandSchema, amountParam, propParam string) DepositEncoder {
+	return func(nonce *big.Int, entry *Entry) ([4]*big.Int, error) {
+		args := map[string]*big.Int{
+			"pid1":      entry.Pid1,
+			"pid2":      entry.Pid2,
+			amountParam: entry.Amount,
+		}
+		if propParam != "" {
+			args[propParam] = entry.Prop
+		} else if entry.Prop != nil && entry.Prop.Sign() != 0 {
+			return [4]*big.Int{}, fmt.Errorf("%w: line %d", ErrPropNotSupported, entry.Line)
+		}
+		return cs.Encode(nonce, args)
+	}
+}
+
+// Operator sends deposit batches signed by the admin key
+type Operator struct {
+	rpc     zkwasm.AppClient
+	signer  zkwasm.Signer
+	encode  DepositEncoder
+	journal Journal
+}
+
+// NewOperator creates an operator sending deposits through rpc. The
+// journal is required; it is what makes a batch resumable.
+func NewOperator(rpc zkwasm.AppClient, signer zkwasm.Signer, encode DepositEncoder, journal Journal) *Operator {
+	return &Operator{rpc: rpc, signer: signer, encode: encode, journal: journal}
+}
+
+// Outcome is the result of one batch entry
+type Outcome struct {
+	Entry Entry
+	// Skipped reports the entry was already done in an earlier run
+	Skipped bool
+	JobID   string
+}
+
+// RunBatch sends the deposits in order, skipping entries the journal shows
+// as done. An entry left pending by a crashed run counts as landed when the
+// admin nonce has moved past the nonce it was signed with, and is sent
+// again otherwise; this assumes nothing else sends with the admin key while
+// a batch is interrupted. The batch stops at the first failure so the nonce
+// check stays meaningful on resume, returning the outcomes so far.
+func (o *Operator) RunBatch(ctx context.Context, entries []Entry) ([]Outcome, error) {
+	var outcomes []Outcome
+	for i := range entries {
+		entry := &entries[i]
+		outcome, err := o.runEntry(ctx, entry)
+		if err != nil {
+			return outcomes, fmt.Errorf("line %d: %w", entry.Line, err)
+		}
+		outcomes = append(outcomes, *outcome)
+	}
+	return outcomes, nil
+}
+
+func (o *Operator) runEntry(ctx context.Context, entry *Entry) (*Outcome, error) {
+	key := entry.Key()
+	nonce, err := o.rpc.GetNoncePkx(ctx, o.signer.Pkx())
+	if err != nil {
+		return nil, err
+	}
+
+	if record, ok := o.journal.Get(key); ok {
+		switch record.Status {
+		case zkwasm.TxFinished:
+			return &Outcome{Entry: *entry, Skipped: true, JobID: record.JobID}, nil
+		case zkwasm.TxSigned:
+			if nonce.Uint64() > record.Nonce {
+				record.Status = zkwasm.TxFinished
+				record.Error = ""
+				if err := o.journal.Save(record); err != nil {
+					return nil, err
+				}
+				return &Outcome{Entry: *entry, Skipped: true, JobID: record.JobID}, nil
+			}
+		}
+	}
+
+	limbs, err := o.encode(nonce, entry)
+	if err != nil {
+		return nil, err
+	}
+	record := zkwasm.TxRecord{ID: key, Pkx: o.signer.Pkx(), Nonce: nonce.Uint64(), Status: zkwasm.TxSigned}
+	if err := o.journal.Save(record); err != nil {
+		return nil, err
+	}
+	result, err := o.rpc.SendTransactionWithSigner(ctx, o.signer, limbs)
+	if err != nil {
+		// the record stays pending; the next run resolves it by nonce
+		record.Error = err.Error()
+		if journalErr := o.journal.Save(record); journalErr != nil {
+			return nil, errors.Join(err, journalErr)
+		}
+		return nil, err
+	}
+	record.Status = zkwasm.TxFinished
+	record.JobID = result.JobID
+	record.Result = result.ReturnValue
+	if err := o.journal.Save(record); err != nil {
+		return nil, err
+	}
+	return &Outcome{Entry: *entry, JobID: result.JobID}, nil
+}
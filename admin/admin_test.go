@@ -0,0 +1,92 @@
+package admin
+
+import (
+	"context"
+	"encoding/json"
+	"errors"
+	"net/http"
+	"net/http/httptest"
+	"strings"
+	"testing"
+
+	"zkwasm-minirollup-rpc-go/zkwasm"
+)
+
+// memJournal is a Journal whose writes can be made to fail
+type memJournal struct {
+	records map[string]zkwasm.TxRecord
+	saves   int
+	failAt  int
+}
+
+func (j *memJournal) Save(record zkwasm.TxRecord) error {
+	j.saves++
+	if j.saves == j.failAt {
+		return errors.New("disk full")
+	}
+	j.records[record.ID] = record
+	return nil
+}
+
+func (j *memJournal) Get(id string) (zkwasm.TxRecord, bool) {
+	record, ok := j.records[id]
+	return record, ok
+}
+
+// rejectingServer reports nonce 5 and rejects every send
+func rejectingServer() *httptest.Server {
+	return httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		switch r.URL.Path {
+		case "/query":
+			data, _ := json.Marshal(map[string]interface{}{"player": map[string]interface{}{"nonce": 5}})
+			w.WriteHeader(http.StatusCreated)
+			json.NewEncoder(w).Encode(map[string]interface{}{"success": true, "data": string(data)})
+		default:
+			http.Error(w, "invalid command", http.StatusBadRequest)
+		}
+	}))
+}
+
+func TestRunBatchReportsJournalError(t *testing.T) {
+	server := rejectingServer()
+	defer server.Close()
+	entries, err := ParseCSV(strings.NewReader("1:2,10\n"))
+	if err != nil {
+		t.Fatal(err)
+	}
+	// the pending record is saved, recording the send failure is not
+	journal := &memJournal{records: make(map[string]zkwasm.TxRecord), failAt: 2}
+	operator := NewOperator(zkwasm.NewZKWasmAppRpc(server.URL), zkwasm.NewKeySigner("1234"), StandardDeposit(8), journal)
+	_, err = operator.RunBatch(context.Background(), entries)
+	if err == nil || !strings.Contains(err.Error(), "disk full") || !strings.Contains(err.Error(), "invalid command") {
+		t.Fatalf("err = %v, want both the send and the journal error", err)
+	}
+	record, ok := journal.Get(entries[0].Key())
+	if !ok || record.Status != zkwasm.TxSigned || record.Nonce != 5 {
+		t.Fatalf("record = %+v, want pending at nonce 5", record)
+	}
+}
+
+func TestRunBatchResolvesPendingByNonce(t *testing.T) {
+	server := rejectingServer()
+	defer server.Close()
+	entries, err := ParseCSV(strings.NewReader("1:2,10\n"))
+	if err != nil {
+		t.Fatal(err)
+	}
+	// a crashed run left the entry pending at nonce 4; the admin nonce is 5
+	journal := &memJournal{records: map[string]zkwasm.TxRecord{
+		entries[0].Key(): {ID: entries[0].Key(), Nonce: 4, Status: zkwasm.TxSigned},
+	}}
+	operator := NewOperator(zkwasm.NewZKWasmAppRpc(server.URL), zkwasm.NewKeySigner("1234"), StandardDeposit(8), journal)
+	outcomes, err := operator.RunBatch(context.Background(), entries)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if len(outcomes) != 1 || !outcomes[0].Skipped {
+		t.Fatalf("outcomes = %+v, want the entry skipped", outcomes)
+	}
+	if record, _ := journal.Get(entries[0].Key()); record.Status != zkwasm.TxFinished {
+		t.Fatalf("record = %+v, want finished", record)
+	}
+}
@@ -0,0 +1,142 @@
+package admin
+
+import (
+	"encoding/csv"
+	"errors"
+	"fmt"
+	"io"
+	"math/big"
+	"strings"
+
+	"zkwasm-minirollup-rpc-go/zkwasm"
+)
+
+var (
+	ErrInvalidEntry = errors.New("InvalidDepositEntry")
+	ErrDuplicateID  = errors.New("DuplicateDepositID")
+)
+
+// Entry is one deposit of a batch
+type Entry struct {
+	// Line is the 1-based CSV line the entry was read from, for messages
+	Line int
+	// ID is the optional id column naming the deposit
+	ID string
+	// Player is the player column as written in the CSV
+	Player string
+	// Pkx is set when the player was given by public key
+	Pkx    string
+	Pid1   *big.Int
+	Pid2   *big.Int
+	Amount *big.Int
+	Prop   *big.Int
+	// occurrence counts earlier entries of the batch with the same content
+	occurrence int
+}
+
+// Key identifies the entry in the journal. An entry with an ID is keyed on
+// it. Otherwise the key is the deposit content, with identical deposits
+// told apart by their order among themselves, so editing or reordering
+// other lines of the CSV keeps the keys of the deposits already done.
+func (e *Entry) Key() string {
+	if e.ID != "" {
+		return "id:" + e.ID
+	}
+	return fmt.Sprintf("%s#%d", e.content(), e.occurrence)
+}
+
+func (e *Entry) content() string {
+	return fmt.Sprintf("%s:%s:%s:%s", e.Pid1, e.Pid2, e.Amount, e.Prop)
+}
+
+// ParseCSV reads deposit entries with the columns player, amount and the
+// optional prop and id. The player is either the little-endian hex pkx of
+// the player public key or a pid written as "pid1:pid2". Ids must be
+// unique within the batch. A first line starting with "player" is treated
+// as a header.
+func ParseCSV(r io.Reader) ([]Entry, error) {
+	reader := csv.NewReader(r)
+	reader.FieldsPerRecord = -1
+	reader.TrimLeadingSpace = true
+	reader.Comment = '#'
+
+	var entries []Entry
+	ids := make(map[string]int)
+	occurrences := make(map[string]int)
+	for {
+		record, err := reader.Read()
+		if err == io.EOF {
+			break
+		}
+		if err != nil {
+			return nil, err
+		}
+		line, _ := reader.FieldPos(0)
+		if len(entries) == 0 && strings.EqualFold(strings.TrimSpace(record[0]), "player") {
+			continue
+		}
+		entry, err := parseEntry(line, record)
+		if err != nil {
+			return nil, err
+		}
+		if entry.ID != "" {
+			if first, ok := ids[entry.ID]; ok {
+				return nil, fmt.Errorf("%w: line %d: id %q is also on line %d", ErrDuplicateID, line, entry.ID, first)
+			}
+			ids[entry.ID] = line
+		}
+		entry.occurrence = occurrences[entry.content()]
+		occurrences[entry.content()]++
+		entries = append(entries, *entry)
+	}
+	return entries, nil
+}
+
+func parseEntry(line int, record []string) (*Entry, error) {
+	if len(record) < 2 || len(record) > 4 {
+		return nil, fmt.Errorf("%w: line %d: expected player, amount[, prop[, id]]", ErrInvalidEntry, line)
+	}
+	entry := &Entry{Line: line, Player: strings.TrimSpace(record[0]), Prop: big.NewInt(0)}
+
+	if pid1, pid2, ok := strings.Cut(entry.Player, ":"); ok {
+		var err error
+		if entry.Pid1, err = parseU64(pid1); err != nil {
+			return nil, fmt.Errorf("%w: line %d: pid1: %v", ErrInvalidEntry, line, err)
+		}
+		if entry.Pid2, err = parseU64(pid2); err != nil {
+			return nil, fmt.Errorf("%w: line %d: pid2: %v", ErrInvalidEntry, line, err)
+		}
+	} else {
+		pid1, pid2, err := zkwasm.PidFromPkx(entry.Player)
+		if err != nil {
+			return nil, fmt.Errorf("%w: line %d: %v", ErrInvalidEntry, line, err)
+		}
+		entry.Pkx = strings.TrimPrefix(entry.Player, "0x")
+		entry.Pid1, entry.Pid2 = pid1, pid2
+	}
+	if err := zkwasm.ValidatePid(entry.Pid1, entry.Pid2); err != nil {
+		return nil, fmt.Errorf("%w: line %d: %v", ErrInvalidEntry, line, err)
+	}
+
+	var err error
+	if entry.Amount, err = parseU64(record[1]); err != nil {
+		return nil, fmt.Errorf("%w: line %d: amount: %v", ErrInvalidEntry, line, err)
+	}
+	if len(record) >= 3 && strings.TrimSpace(record[2]) != "" {
+		if entry.Prop, err = parseU64(record[2]); err != nil {
+			return nil, fmt.Errorf("%w: line %d: prop: %v", ErrInvalidEntry, line, err)
+		}
+	}
+	if len(record) == 4 {
+		entry.ID = strings.TrimSpace(record[3])
+	}
+	return entry, nil
+}
+
+func parseU64(s string) (*big.Int, error) {
+	v, ok := new(big.Int).SetString(strings.TrimSpace(s), 10)
+	if !ok || v.Sign() < 0 || v.BitLen() > 64 {
+		return nil, fmt.Errorf("%q is not a u64", s)
+	}
+	return v, nil
+}
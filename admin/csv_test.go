@@ -0,0 +1,49 @@
+package admin
+
+import (
+	"errors"
+	"strings"
+	"testing"
+)
+
+func keys(t *testing.T, csv string) []string {
+	t.Helper()
+	entries, err := ParseCSV(strings.NewReader(csv))
+	if err != nil {
+		t.Fatal(err)
+	}
+	var out []string
+	for i := range entries {
+		out = append(out, entries[i].Key())
+	}
+	return out
+}
+
+func TestEntryKeyIgnoresLinePosition(t *testing.T) {
+	before := keys(t, "player,amount\n1:2,10\n3:4,20\n1:2,10\n")
+	// a header removed, a line inserted and the lines reordered
+	after := keys(t, "# resumed\n5:6,30\n3:4,20\n1:2,10\n1:2,10\n")
+	for _, key := range before {
+		found := false
+		for _, other := range after {
+			found = found || key == other
+		}
+		if !found {
+			t.Errorf("key %s changed after editing the CSV: %v", key, after)
+		}
+	}
+	if before[0] == before[2] {
+		t.Errorf("identical deposits share the key %s", before[0])
+	}
+}
+
+func TestEntryKeyUsesID(t *testing.T) {
+	got := keys(t, "1:2,10,,a\n1:2,10,0,b\n")
+	if got[0] != "id:a" || got[1] != "id:b" {
+		t.Fatalf("keys = %v", got)
+	}
+	_, err := ParseCSV(strings.NewReader("1:2,10,,a\n3:4,20,,a\n"))
+	if !errors.Is(err, ErrDuplicateID) {
+		t.Fatalf("err = %v, want ErrDuplicateID", err)
+	}
+}
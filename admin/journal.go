@@ -0,0 +1,18 @@
+package admin
+
+import "zkwasm-minirollup-rpc-go/zkwasm"
+
+// Journal records the progress of a deposit batch so an interrupted run
+// can resume without sending the same deposit twice. Records are keyed by
+// Entry.Key: a zkwasm.TxSigned record is written before a deposit is sent
+// and may or may not have landed, a zkwasm.TxFinished one once its job
+// finished.
+//
+// zkwasm.FileTxJournal implements Journal. Give the operator a journal file
+// of its own, not the one passed to zkwasm.WithJournal: the batch records
+// carry no signed payload for ZKWasmAppRpc.Recover to resend. Compact it
+// with a nil filter only: finished records are what mark deposits done.
+type Journal interface {
+	Save(record zkwasm.TxRecord) error
+	Get(id string) (zkwasm.TxRecord, bool)
+}
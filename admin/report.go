@@ -0,0 +1,179 @@
+package admin
+
+import (
+	"context"
+	"encoding/csv"
+	"fmt"
+	"io"
+	"math/big"
+	"strconv"
+
+	"zkwasm-minirollup-rpc-go/zkwasm"
+)
+
+// BalanceFunc extracts the balance credited by deposits from the decoded
+// state data of a player
+type BalanceFunc func(state map[string]interface{}) (*big.Int, error)
+
+// PlayerReport compares the deposits credited to one player in a batch
+// with the player's queried state
+type PlayerReport struct {
+	Player   string
+	Pid1     *big.Int
+	Pid2     *big.Int
+	Deposits int
+	Credited *big.Int
+	// Before is the balance snapshot taken before the batch, nil when the
+	// snapshot has none for the player
+	Before *big.Int
+	// Balance is nil when the player was given by pid only, since the
+	// state can only be queried by public key
+	Balance *big.Int
+	// Mismatch reports a balance lower than Before plus the credited total
+	Mismatch bool
+	Error    string
+}
+
+// Balances holds the balance of each player of a batch by pid, see
+// SnapshotBalances
+type Balances map[string]*big.Int
+
+func pidKey(pid1, pid2 *big.Int) string {
+	return pid1.String() + ":" + pid2.String()
+}
+
+// Get returns the snapshot balance of the player pid
+func (b Balances) Get(pid1, pid2 *big.Int) (*big.Int, bool) {
+	balance, ok := b[pidKey(pid1, pid2)]
+	return balance, ok
+}
+
+// SnapshotBalances queries the balance of every player of the batch given
+// by public key. Take it before the first RunBatch of a batch: a snapshot
+// taken on resume already includes the deposits credited so far.
+func (o *Operator) SnapshotBalances(ctx context.Context, entries []Entry, balance BalanceFunc) (Balances, error) {
+	balances := make(Balances)
+	for i := range entries {
+		entry := &entries[i]
+		key := pidKey(entry.Pid1, entry.Pid2)
+		if _, ok := balances[key]; ok || entry.Pkx == "" {
+			continue
+		}
+		state, err := o.queryState(ctx, entry.Pkx)
+		if err != nil {
+			return nil, fmt.Errorf("line %d: %w", entry.Line, err)
+		}
+		if balances[key], err = balance(state); err != nil {
+			return nil, fmt.Errorf("line %d: %w", entry.Line, err)
+		}
+	}
+	return balances, nil
+}
+
+// Report is the reconciliation of a batch against player states
+type Report struct {
+	Players []*PlayerReport
+}
+
+// Reconcile sums the journal-confirmed deposits per player and queries the
+// state of every player given by public key, reading its balance with
+// balance and comparing it with the before snapshot plus the credited
+// total. Spending by a player between the snapshot and Reconcile shows as
+// a mismatch too. Query failures are reported per player rather than
+// aborting.
+func (o *Operator) Reconcile(ctx context.Context, entries []Entry, before Balances, balance BalanceFunc) (*Report, error) {
+	report := &Report{}
+	byPid := make(map[string]*PlayerReport)
+	pkxs := make(map[string]string)
+	for i := range entries {
+		entry := &entries[i]
+		record, ok := o.journal.Get(entry.Key())
+		if !ok || record.Status != zkwasm.TxFinished {
+			continue
+		}
+		pid := pidKey(entry.Pid1, entry.Pid2)
+		player, ok := byPid[pid]
+		if !ok {
+			player = &PlayerReport{Player: entry.Player, Pid1: entry.Pid1, Pid2: entry.Pid2, Credited: big.NewInt(0)}
+			byPid[pid] = player
+			report.Players = append(report.Players, player)
+		}
+		player.Deposits++
+		player.Credited.Add(player.Credited, entry.Amount)
+		if entry.Pkx != "" {
+			pkxs[pid] = entry.Pkx
+		}
+	}
+
+	for _, player := range report.Players {
+		pkx, ok := pkxs[pidKey(player.Pid1, player.Pid2)]
+		if !ok || balance == nil {
+			continue
+		}
+		player.Before, _ = before.Get(player.Pid1, player.Pid2)
+		state, err := o.queryState(ctx, pkx)
+		if err != nil {
+			player.Error = err.Error()
+			continue
+		}
+		player.Balance, err = balance(state)
+		if err != nil {
+			player.Error = err.Error()
+			continue
+		}
+		if player.Before == nil {
+			player.Error = "no balance before the batch"
+			continue
+		}
+		expected := new(big.Int).Add(player.Before, player.Credited)
+		player.Mismatch = player.Balance.Cmp(expected) < 0
+	}
+	return report, nil
+}
+
+func (o *Operator) queryState(ctx context.Context, pkx string) (map[string]interface{}, error) {
+	resp, err := o.rpc.QueryStatePkx(ctx, pkx)
+	if err != nil {
+		return nil, err
+	}
+	return zkwasm.DecodeStateData(resp)
+}
+
+// Mismatches returns the players whose balance is below the expected one
+func (r *Report) Mismatches() []*PlayerReport {
+	var mismatches []*PlayerReport
+	for _, player := range r.Players {
+		if player.Mismatch {
+			mismatches = append(mismatches, player)
+		}
+	}
+	return mismatches
+}
+
+// WriteCSV writes the report as CSV with a header line
+func (r *Report) WriteCSV(w io.Writer) error {
+	writer := csv.NewWriter(w)
+	writer.Write([]string{"player", "pid1", "pid2", "deposits", "credited", "before", "balance", "mismatch", "error"})
+	for _, player := range r.Players {
+		writer.Write([]string{
+			player.Player,
+			player.Pid1.String(),
+			player.Pid2.String(),
+			strconv.Itoa(player.Deposits),
+			player.Credited.String(),
+			optionalInt(player.Before),
+			optionalInt(player.Balance),
+			strconv.FormatBool(player.Mismatch),
+			player.Error,
+		})
+	}
+	writer.Flush()
+	return writer.Error()
+}
+
+func optionalInt(v *big.Int) string {
+	if v == nil {
+		return ""
+	}
+	return v.String()
+}
@@ -0,0 +1,129 @@
+package admin
+
+import (
+	"bytes"
+	"context"
+	"encoding/json"
+	"fmt"
+	"math/big"
+	"net/http"
+	"net/http/httptest"
+	"strings"
+	"testing"
+
+	"zkwasm-minirollup-rpc-go/zkwasm"
+)
+
+// balanceServer answers /query with the balance it holds for each pkx
+func balanceServer(balances map[string]uint64) *httptest.Server {
+	return httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		var payload map[string]string
+		json.NewDecoder(r.Body).Decode(&payload)
+		balance, ok := balances[payload["pkx"]]
+		if !ok {
+			http.Error(w, "unknown player", http.StatusBadRequest)
+			return
+		}
+		data, _ := json.Marshal(map[string]interface{}{"player": map[string]interface{}{"nonce": 1, "data": map[string]interface{}{"balance": balance}}})
+		w.WriteHeader(http.StatusCreated)
+		json.NewEncoder(w).Encode(map[string]interface{}{"success": true, "data": string(data)})
+	}))
+}
+
+func testBalance(state map[string]interface{}) (*big.Int, error) {
+	player, _ := state["player"].(map[string]interface{})
+	data, _ := player["data"].(map[string]interface{})
+	balance, ok := data["balance"].(float64)
+	if !ok {
+		return nil, fmt.Errorf("no balance in %v", state)
+	}
+	return big.NewInt(int64(balance)), nil
+}
+
+func TestReconcileAgainstSnapshot(t *testing.T) {
+	alice := zkwasm.Query("1234")["pkx"]
+	bob := zkwasm.Query("5678")["pkx"]
+	balances := map[string]uint64{alice: 100, bob: 3}
+	server := balanceServer(balances)
+	defer server.Close()
+
+	entries, err := ParseCSV(strings.NewReader(alice + ",10\n" + alice + ",5\n" + bob + ",1\n1:2,7\n"))
+	if err != nil {
+		t.Fatal(err)
+	}
+	journal := &memJournal{records: make(map[string]zkwasm.TxRecord)}
+	for _, entry := range entries {
+		journal.Save(zkwasm.TxRecord{ID: entry.Key(), Status: zkwasm.TxFinished})
+	}
+	operator := NewOperator(zkwasm.NewZKWasmAppRpc(server.URL), zkwasm.NewKeySigner("1234"), StandardDeposit(8), journal)
+	ctx := context.Background()
+
+	before, err := operator.SnapshotBalances(ctx, entries, testBalance)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if len(before) != 2 {
+		t.Fatalf("snapshot = %v, want alice and bob", before)
+	}
+
+	// alice is credited in full; bob already had 3 so a balance of 3
+	// after a deposit of 1 is short even though it covers the deposit
+	balances[alice] = 115
+	report, err := operator.Reconcile(ctx, entries, before, testBalance)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if len(report.Players) != 3 {
+		t.Fatalf("players = %d, want 3", len(report.Players))
+	}
+	a, b, pid := report.Players[0], report.Players[1], report.Players[2]
+	if a.Deposits != 2 || a.Credited.Int64() != 15 || a.Before.Int64() != 100 || a.Mismatch {
+		t.Fatalf("alice = %+v", a)
+	}
+	if !b.Mismatch || b.Balance.Int64() != 3 {
+		t.Fatalf("bob = %+v, want a mismatch", b)
+	}
+	if pid.Balance != nil || pid.Mismatch {
+		t.Fatalf("pid player = %+v, want no balance", pid)
+	}
+	if m := report.Mismatches(); len(m) != 1 || m[0] != b {
+		t.Fatalf("mismatches = %+v", m)
+	}
+
+	var out bytes.Buffer
+	if err := report.WriteCSV(&out); err != nil {
+		t.Fatal(err)
+	}
+	lines := strings.Split(strings.TrimSpace(out.String()), "\n")
+	if want := "player,pid1,pid2,deposits,credited,before,balance,mismatch,error"; lines[0] != want {
+		t.Fatalf("header = %s", lines[0])
+	}
+	if want := fmt.Sprintf("%s,%s,%s,2,15,100,115,false,", alice, a.Pid1, a.Pid2); lines[1] != want {
+		t.Fatalf("alice line = %s, want %s", lines[1], want)
+	}
+	if want := "1:2,1,2,1,7,,,false,"; lines[3] != want {
+		t.Fatalf("pid line = %s, want %s", lines[3], want)
+	}
+
+	// a player missing from the snapshot cannot be checked
+	report, err = operator.Reconcile(ctx, entries, Balances{}, testBalance)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if p := report.Players[0]; p.Mismatch || p.Error == "" {
+		t.Fatalf("player without snapshot = %+v, want an error", p)
+	}
+}
+
+func TestSnapshotBalancesFails(t *testing.T) {
+	server := balanceServer(map[string]uint64{})
+	defer server.Close()
+	entries, err := ParseCSV(strings.NewReader(zkwasm.Query("1234")["pkx"] + ",10\n"))
+	if err != nil {
+		t.Fatal(err)
+	}
+	operator := NewOperator(zkwasm.NewZKWasmAppRpc(server.URL), zkwasm.NewKeySigner("1234"), StandardDeposit(8), &memJournal{records: make(map[string]zkwasm.TxRecord)})
+	if _, err := operator.SnapshotBalances(context.Background(), entries, testBalance); err == nil || !strings.Contains(err.Error(), "line 1") {
+		t.Fatalf("err = %v, want the failing line", err)
+	}
+}
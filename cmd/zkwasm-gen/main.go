@@ -0,0 +1,291 @@
+// Command zkwasm-gen generates a typed Go client for a zkWasm application
+// from a command schema (see zkwasm.Schema).
+//
+// Typical use from the package that should hold the client:
+//
+//	//go:generate go run zkwasm-minirollup-rpc-go/cmd/zkwasm-gen -schema schema.json -pkg ranch -out client_gen.go
+//
+// Every schema command becomes a method taking a context and one uint64 per
+// parameter, e.g. client.BuyElf(ctx, ranchID, elfType). The method fetches
+// the current nonce, encodes the limbs through the schema and waits for the
+// transaction job. Commands marked admin are generated on a separate
+// Admin<type> client meant to be constructed with the admin key.
+package main
+
+import (
+	"bytes"
+	"flag"
+	"fmt"
+	"go/format"
+	"go/token"
+	"log"
+	"os"
+	"strings"
+	"text/template"
+	"unicode"
+
+	"zkwasm-minirollup-rpc-go/zkwasm"
+)
+
+type paramData struct {
+	Name  string
+	Ident string
+}
+
+type commandData struct {
+	Method string
+	Name   string
+	ID     uint64
+	Params []paramData
+}
+
+type fileData struct {
+	Package       string
+	Type          string
+	Prefix        string
+	SchemaJSON    string
+	Commands      []commandData
+	AdminCommands []commandData
+}
+
+var clientTemplate = template.Must(template.New("client").Funcs(template.FuncMap{"goString": goString}).Parse(`// Code generated by zkwasm-gen. DO NOT EDIT.
+
+package {{.Package}}
+
+import (
+	"context"
+	"math/big"
+
+	"zkwasm-minirollup-rpc-go/zkwasm"
+)
+
+const {{.Prefix}}SchemaJSON = {{goString .SchemaJSON}}
+
+// {{.Type}}Schema is the command schema {{.Type}} was generated from
+var {{.Type}}Schema = func() *zkwasm.Schema {
+	schema, err := zkwasm.ParseSchema([]byte({{.Prefix}}SchemaJSON))
+	if err != nil {
+		panic(err)
+	}
+	return schema
+}()
+
+// {{.Type}} sends the application commands signed with a player key
+// through a ZKWasmAppRpc or a ZKWasmPool
+type {{.Type}} struct {
+	rpc    zkwasm.AppClient
+	prikey string
+}
+
+// New{{.Type}} creates a client sending commands through rpc signed by prikey
+func New{{.Type}}(rpc zkwasm.AppClient, prikey string) *{{.Type}} {
+	return &{{.Type}}{rpc: rpc, prikey: prikey}
+}
+
+// Send encodes the named command with the current nonce and waits for the
+// transaction job to finish
+func (c *{{.Type}}) Send(ctx context.Context, name string, args map[string]*big.Int) (*zkwasm.TransactionResult, error) {
+	return {{$.Prefix}}Send(ctx, c.rpc, c.prikey, name, args)
+}
+{{range .Commands}}
+// {{.Method}} sends the {{.Name}} command (id {{.ID}})
+func (c *{{$.Type}}) {{.Method}}(ctx context.Context{{range .Params}}, {{.Ident}} uint64{{end}}) (*zkwasm.TransactionResult, error) {
+	return c.Send(ctx, {{printf "%q" .Name}}, map[string]*big.Int{
+{{- range .Params}}
+		{{printf "%q" .Name}}: new(big.Int).SetUint64({{.Ident}}),
+{{- end}}
+	})
+}
+{{end}}{{if .AdminCommands}}
+// Admin{{.Type}} sends the admin-only commands signed with the admin key
+type Admin{{.Type}} struct {
+	rpc    zkwasm.AppClient
+	prikey string
+}
+
+// NewAdmin{{.Type}} creates an admin client sending commands through rpc
+// signed by adminKey
+func NewAdmin{{.Type}}(rpc zkwasm.AppClient, adminKey string) *Admin{{.Type}} {
+	return &Admin{{.Type}}{rpc: rpc, prikey: adminKey}
+}
+
+// Send encodes the named command with the admin nonce and waits for the
+// transaction job to finish
+func (c *Admin{{.Type}}) Send(ctx context.Context, name string, args map[string]*big.Int) (*zkwasm.TransactionResult, error) {
+	return {{$.Prefix}}Send(ctx, c.rpc, c.prikey, name, args)
+}
+{{range .AdminCommands}}
+// {{.Method}} sends the admin {{.Name}} command (id {{.ID}})
+func (c *Admin{{$.Type}}) {{.Method}}(ctx context.Context{{range .Params}}, {{.Ident}} uint64{{end}}) (*zkwasm.TransactionResult, error) {
+	return c.Send(ctx, {{printf "%q" .Name}}, map[string]*big.Int{
+{{- range .Params}}
+		{{printf "%q" .Name}}: new(big.Int).SetUint64({{.Ident}}),
+{{- end}}
+	})
+}
+{{end}}{{end}}
+func {{.Prefix}}Send(ctx context.Context, rpc zkwasm.AppClient, prikey string, name string, args map[string]*big.Int) (*zkwasm.TransactionResult, error) {
+	cs, err := {{.Type}}Schema.Lookup(name)
+	if err != nil {
+		return nil, err
+	}
+	nonce, err := rpc.GetNonceContext(ctx, prikey)
+	if err != nil {
+		return nil, err
+	}
+	limbs, err := cs.Encode(nonce, args)
+	if err != nil {
+		return nil, err
+	}
+	return rpc.SendTransactionResultContext(ctx, limbs, prikey)
+}
+`))
+
+// goString quotes s as a raw string literal when possible so the embedded
+// schema stays readable
+func goString(s string) string {
+	if strings.Contains(s, "`") {
+		return fmt.Sprintf("%q", s)
+	}
+	return "`" + s + "`"
+}
+
+// goIdent turns a schema name into a Go identifier, upper-casing the first
+// letter when exported and spelling a trailing Id word as ID. Words are
+// split at separators and at lower-to-upper case changes, so ranch_id and
+// ranchId end in the word Id but Valid does not.
+func goIdent(name string, exported bool) string {
+	var sb strings.Builder
+	upperNext := exported
+	lastWord := 0
+	prevLower := false
+	for i, r := range name {
+		switch {
+		case r == '_' || r == '-' || r == ' ':
+			upperNext = true
+			lastWord = sb.Len()
+			prevLower = false
+			continue
+		case unicode.IsUpper(r) && prevLower:
+			lastWord = sb.Len()
+		}
+		switch {
+		case upperNext:
+			sb.WriteRune(unicode.ToUpper(r))
+			upperNext = false
+		case i == 0:
+			sb.WriteRune(unicode.ToLower(r))
+		default:
+			sb.WriteRune(r)
+		}
+		prevLower = unicode.IsLower(r) || unicode.IsDigit(r)
+	}
+	ident := sb.String()
+	if ident[lastWord:] == "Id" {
+		ident = ident[:lastWord] + "ID"
+	}
+	if ident == "" || !unicode.IsLetter([]rune(ident)[0]) {
+		ident = "x" + ident
+	}
+	// avoid keywords, the receiver and context names of the template and
+	// the identifiers its method bodies refer to
+	if token.IsKeyword(ident) || reservedIdents[ident] {
+		ident += "Arg"
+	}
+	return ident
+}
+
+// reservedIdents are the names a generated parameter must not shadow
+var reservedIdents = map[string]bool{
+	"c": true, "ctx": true, "context": true, "big": true, "zkwasm": true, "new": true,
+}
+
+// generate renders the client source for a raw JSON schema
+func generate(raw []byte, pkg, typeName string) ([]byte, error) {
+	schema, err := zkwasm.ParseSchema(raw)
+	if err != nil {
+		return nil, err
+	}
+	data := fileData{
+		Package:    pkg,
+		Type:       typeName,
+		Prefix:     goIdent(typeName, false),
+		SchemaJSON: string(bytes.TrimSpace(raw)),
+	}
+	for _, cs := range schema.Commands {
+		cmd := commandData{Method: goIdent(cs.Name, true), Name: cs.Name, ID: cs.ID}
+		for _, p := range cs.Params {
+			cmd.Params = append(cmd.Params, paramData{Name: p.Name, Ident: goIdent(p.Name, false)})
+		}
+		if cs.Admin {
+			data.AdminCommands = append(data.AdminCommands, cmd)
+		} else {
+			data.Commands = append(data.Commands, cmd)
+		}
+	}
+	if err := checkCollisions(typeName, data.Commands); err != nil {
+		return nil, err
+	}
+	if err := checkCollisions("Admin"+typeName, data.AdminCommands); err != nil {
+		return nil, err
+	}
+
+	var buf bytes.Buffer
+	if err := clientTemplate.Execute(&buf, data); err != nil {
+		return nil, err
+	}
+	src, err := format.Source(buf.Bytes())
+	if err != nil {
+		return nil, fmt.Errorf("formatting generated code: %w", err)
+	}
+	return src, nil
+}
+
+// checkCollisions reports schema names that map to the same Go identifier:
+// two methods of the client type, a method and the generated Send, or two
+// parameters of one command
+func checkCollisions(typeName string, commands []commandData) error {
+	methods := map[string]string{"Send": "the generated Send method"}
+	for _, cmd := range commands {
+		if other, ok := methods[cmd.Method]; ok {
+			return fmt.Errorf("command %q and %s both map to %s.%s", cmd.Name, other, typeName, cmd.Method)
+		}
+		methods[cmd.Method] = fmt.Sprintf("command %q", cmd.Name)
+		params := make(map[string]string)
+		for _, p := range cmd.Params {
+			if other, ok := params[p.Ident]; ok {
+				return fmt.Errorf("command %q: parameters %q and %q both map to %s", cmd.Name, other, p.Name, p.Ident)
+			}
+			params[p.Ident] = p.Name
+		}
+	}
+	return nil
+}
+
+func main() {
+	schemaPath := flag.String("schema", "schema.json", "command schema JSON file")
+	pkg := flag.String("pkg", "", "package name of the generated file")
+	out := flag.String("out", "client_gen.go", "output file")
+	typeName := flag.String("type", "Client", "name of the generated client type")
+	flag.Parse()
+
+	if *pkg == "" {
+		*pkg = os.Getenv("GOPACKAGE")
+	}
+	if *pkg == "" {
+		log.Fatal("zkwasm-gen: -pkg is required outside go generate")
+	}
+
+	raw, err := os.ReadFile(*schemaPath)
+	if err != nil {
+		log.Fatalf("zkwasm-gen: %v", err)
+	}
+	src, err := generate(raw, *pkg, *typeName)
+	if err != nil {
+		log.Fatalf("zkwasm-gen: %s: %v", *schemaPath, err)
+	}
+	if err := os.WriteFile(*out, src, 0644); err != nil {
+		log.Fatalf("zkwasm-gen: %v", err)
+	}
+	fmt.Printf("zkwasm-gen: wrote %s\n", *out)
+}
@@ -0,0 +1,130 @@
+package main
+
+import (
+	"bytes"
+	"flag"
+	"os"
+	"path/filepath"
+	"strings"
+	"testing"
+)
+
+var update = flag.Bool("update", false, "rewrite the golden files")
+
+func TestGenerateGolden(t *testing.T) {
+	raw, err := os.ReadFile(filepath.Join("testdata", "schema.json"))
+	if err != nil {
+		t.Fatal(err)
+	}
+	got, err := generate(raw, "game", "Client")
+	if err != nil {
+		t.Fatal(err)
+	}
+	golden := filepath.Join("testdata", "client.golden")
+	if *update {
+		if err := os.WriteFile(golden, got, 0644); err != nil {
+			t.Fatal(err)
+		}
+	}
+	want, err := os.ReadFile(golden)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if !bytes.Equal(got, want) {
+		t.Errorf("generated client differs from %s; rerun with -update if the change is intended\n%s", golden, got)
+	}
+}
+
+// TestRanchClientUpToDate keeps the checked-in ranch client in step with
+// its schema and the generator
+func TestRanchClientUpToDate(t *testing.T) {
+	raw, err := os.ReadFile(filepath.Join("..", "..", "ranch", "schema.json"))
+	if err != nil {
+		t.Fatal(err)
+	}
+	got, err := generate(raw, "ranch", "Client")
+	if err != nil {
+		t.Fatal(err)
+	}
+	want, err := os.ReadFile(filepath.Join("..", "..", "ranch", "client_gen.go"))
+	if err != nil {
+		t.Fatal(err)
+	}
+	if !bytes.Equal(got, want) {
+		t.Error("ranch/client_gen.go is stale; run go generate ./ranch")
+	}
+}
+
+func TestGenerateCollisions(t *testing.T) {
+	cases := []struct {
+		name   string
+		schema string
+		want   string
+	}{
+		{
+			"commands",
+			`{"commands": [{"name": "ranch_id", "id": 1}, {"name": "ranchId", "id": 2}]}`,
+			`command "ranchId" and command "ranch_id" both map to Client.RanchID`,
+		},
+		{
+			"send method",
+			`{"commands": [{"name": "send", "id": 1}]}`,
+			`command "send" and the generated Send method both map to Client.Send`,
+		},
+		{
+			"admin send method",
+			`{"commands": [{"name": "Send", "id": 1, "admin": true}]}`,
+			`both map to AdminClient.Send`,
+		},
+		{
+			"params",
+			`{"commands": [{"name": "buy", "id": 1, "params": [
+				{"name": "elf_type", "limb": 1}, {"name": "elfType", "limb": 2}]}]}`,
+			`command "buy": parameters "elf_type" and "elfType" both map to elfType`,
+		},
+		{
+			"reserved suffix",
+			`{"commands": [{"name": "buy", "id": 1, "params": [
+				{"name": "ctx", "limb": 1}, {"name": "ctx_arg", "limb": 2}]}]}`,
+			`parameters "ctx" and "ctx_arg" both map to ctxArg`,
+		},
+	}
+	for _, c := range cases {
+		_, err := generate([]byte(c.schema), "game", "Client")
+		if err == nil || !strings.Contains(err.Error(), c.want) {
+			t.Errorf("%s: err = %v, want it to contain %q", c.name, err, c.want)
+		}
+	}
+	// the same method name on the player and admin clients is fine
+	ok := `{"commands": [{"name": "reset", "id": 1}, {"name": "Reset", "id": 2, "admin": true}]}`
+	if _, err := generate([]byte(ok), "game", "Client"); err != nil {
+		t.Errorf("player and admin methods: %v", err)
+	}
+}
+
+func TestGoIdent(t *testing.T) {
+	cases := []struct {
+		name     string
+		exported bool
+		want     string
+	}{
+		{"ranchId", false, "ranchID"},
+		{"ranch_id", false, "ranchID"},
+		{"buy-elf-id", true, "BuyElfID"},
+		{"id", true, "ID"},
+		{"id", false, "id"},
+		{"Valid", true, "Valid"},
+		{"isValid", false, "isValid"},
+		{"VALId", true, "VALId"},
+		{"userid", false, "userid"},
+		{"elf2Id", false, "elf2ID"},
+		{"type", false, "typeArg"},
+		{"ctx", false, "ctxArg"},
+		{"2fa", false, "x2fa"},
+	}
+	for _, c := range cases {
+		if got := goIdent(c.name, c.exported); got != c.want {
+			t.Errorf("goIdent(%q, %v) = %s, want %s", c.name, c.exported, got, c.want)
+		}
+	}
+}
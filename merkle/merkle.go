@@ -0,0 +1,181 @@
+// Package merkle implements the Poseidon sparse Merkle tree committing the
+// zkWasm rollup state, following the layout of the zkWasm host MongoMerkle:
+// nodes are numbered from the root at 0 with children 2i+1 and 2i+2, a node
+// hashes its children with Poseidon, and a leaf hashes its 32 data bytes
+// as two 128-bit field elements.
+package merkle
+
+import (
+	"encoding/hex"
+	"errors"
+	"fmt"
+	"math/big"
+	"strings"
+
+	"zkwasm-minirollup-rpc-go/zkwasm"
+)
+
+// DefaultDepth is the depth of the rollup state tree
+const DefaultDepth = 32
+
+var (
+	ErrInvalidProof     = errors.New("InvalidProof")
+	ErrRootMismatch     = errors.New("RootMismatch")
+	ErrIndexOutOfRange  = errors.New("IndexOutOfRange")
+	ErrInvalidHash      = errors.New("InvalidHash")
+	ErrInvalidTreeDepth = errors.New("InvalidTreeDepth")
+)
+
+// Hash is a field element in its 32-byte little-endian form
+type Hash [32]byte
+
+// ParseHash reads a hex hash, with or without 0x prefix
+func ParseHash(s string) (Hash, error) {
+	var h Hash
+	b, err := hex.DecodeString(strings.TrimPrefix(s, "0x"))
+	if err != nil || len(b) != len(h) {
+		return h, fmt.Errorf("%w: %s", ErrInvalidHash, s)
+	}
+	copy(h[:], b)
+	if _, err := h.field(); err != nil {
+		return h, err
+	}
+	return h, nil
+}
+
+func (h Hash) String() string {
+	return hex.EncodeToString(h[:])
+}
+
+func (h Hash) MarshalText() ([]byte, error) {
+	return []byte(h.String()), nil
+}
+
+func (h *Hash) UnmarshalText(text []byte) error {
+	parsed, err := ParseHash(string(text))
+	if err != nil {
+		return err
+	}
+	*h = parsed
+	return nil
+}
+
+func (h Hash) field() (*zkwasm.Field, error) {
+	f, err := zkwasm.FieldFromBytesLE(h[:])
+	if err != nil {
+		return nil, fmt.Errorf("%w: %s is not a field element", ErrInvalidHash, h)
+	}
+	return f, nil
+}
+
+func hashOf(f *zkwasm.Field) Hash {
+	return Hash(f.BytesLE())
+}
+
+// HashLeaf hashes leaf data: each 16-byte half is read as a little-endian
+// field element and the two are hashed with Poseidon
+func HashLeaf(data [32]byte) Hash {
+	var lo, hi [16]byte
+	copy(lo[:], data[:16])
+	copy(hi[:], data[16:])
+	return hashOf(zkwasm.PoseidonHash(halfField(lo), halfField(hi)))
+}
+
+func halfField(half [16]byte) *zkwasm.Field {
+	be := make([]byte, 16)
+	for i := range half {
+		be[15-i] = half[i]
+	}
+	return zkwasm.NewField(new(big.Int).SetBytes(be))
+}
+
+// HashNode hashes the children of an inner node
+func HashNode(left, right Hash) (Hash, error) {
+	l, err := left.field()
+	if err != nil {
+		return Hash{}, err
+	}
+	r, err := right.field()
+	if err != nil {
+		return Hash{}, err
+	}
+	return hashOf(zkwasm.PoseidonHash(l, r)), nil
+}
+
+// DefaultHashes returns the hash of an empty subtree at each level, from
+// the empty leaf at index 0 up to the empty root at index depth
+func DefaultHashes(depth int) []Hash {
+	hashes := make([]Hash, depth+1)
+	hashes[0] = HashLeaf([32]byte{})
+	for i := 1; i <= depth; i++ {
+		// default hashes are canonical so hashing cannot fail
+		hashes[i], _ = HashNode(hashes[i-1], hashes[i-1])
+	}
+	return hashes
+}
+
+// LeafIndex returns the global node index of the leaf at offset
+func LeafIndex(depth int, offset uint64) uint64 {
+	return (uint64(1) << uint(depth)) - 1 + offset
+}
+
+// Proof shows that Leaf is the hash at Index under Root. Siblings run
+// from the leaf level up to the children of the root.
+type Proof struct {
+	// Index is the global node index of the leaf
+	Index    uint64 `json:"index"`
+	Leaf     Hash   `json:"leaf"`
+	Siblings []Hash `json:"siblings"`
+	Root     Hash   `json:"root"`
+}
+
+// ComputeRoot folds the siblings over the leaf
+func (p *Proof) ComputeRoot() (Hash, error) {
+	depth := len(p.Siblings)
+	if depth == 0 || depth > 63 {
+		return Hash{}, fmt.Errorf("%w: %d siblings", ErrInvalidProof, depth)
+	}
+	first := LeafIndex(depth, 0)
+	if p.Index < first || p.Index > 2*first {
+		return Hash{}, fmt.Errorf("%w: index %d is not a leaf of a depth %d tree", ErrIndexOutOfRange, p.Index, depth)
+	}
+	hash, index := p.Leaf, p.Index
+	for _, sibling := range p.Siblings {
+		var err error
+		// odd nodes are left children
+		if index%2 == 1 {
+			hash, err = HashNode(hash, sibling)
+		} else {
+			hash, err = HashNode(sibling, hash)
+		}
+		if err != nil {
+			return Hash{}, err
+		}
+		index = (index - 1) / 2
+	}
+	return hash, nil
+}
+
+// Verify checks the proof against its own root and the trusted root
+func (p *Proof) Verify(root Hash) error {
+	computed, err := p.ComputeRoot()
+	if err != nil {
+		return err
+	}
+	if computed != p.Root {
+		return fmt.Errorf("%w: proof computes %s, claims %s", ErrInvalidProof, computed, p.Root)
+	}
+	if computed != root {
+		return fmt.Errorf("%w: proof computes %s, trusted %s", ErrRootMismatch, computed, root)
+	}
+	return nil
+}
+
+// VerifyData checks that data is the leaf of the proof and the proof
+// against root
+func (p *Proof) VerifyData(data [32]byte, root Hash) error {
+	if HashLeaf(data) != p.Leaf {
+		return fmt.Errorf("%w: leaf does not hash data", ErrInvalidProof)
+	}
+	return p.Verify(root)
+}
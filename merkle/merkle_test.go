@@ -0,0 +1,113 @@
+package merkle
+
+import (
+	"errors"
+	"testing"
+)
+
+func leafData(b byte) [32]byte {
+	var data [32]byte
+	for i := range data {
+		data[i] = b + byte(i)
+	}
+	return data
+}
+
+var testOffsets = []uint64{0, 5, 1<<31 + 3, 1<<32 - 1}
+
+func testTree(t *testing.T) *Tree {
+	t.Helper()
+	tree, err := NewTree(DefaultDepth)
+	if err != nil {
+		t.Fatal(err)
+	}
+	for i, offset := range testOffsets {
+		if err := tree.Set(offset, leafData(byte(i+1))); err != nil {
+			t.Fatal(err)
+		}
+	}
+	return tree
+}
+
+func TestProveVerifyRoundTrip(t *testing.T) {
+	tree := testTree(t)
+	root := tree.Root()
+	for i, offset := range testOffsets {
+		proof, err := tree.Prove(offset)
+		if err != nil {
+			t.Fatal(err)
+		}
+		if len(proof.Siblings) != DefaultDepth || proof.Index != LeafIndex(DefaultDepth, offset) {
+			t.Fatalf("proof of %d has %d siblings at index %d", offset, len(proof.Siblings), proof.Index)
+		}
+		if err := proof.VerifyData(leafData(byte(i+1)), root); err != nil {
+			t.Fatalf("proof of %d: %v", offset, err)
+		}
+	}
+	// an unset leaf proves the empty data
+	proof, err := tree.Prove(6)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if err := proof.VerifyData([32]byte{}, root); err != nil {
+		t.Fatalf("proof of an empty leaf: %v", err)
+	}
+}
+
+func TestVerifyRejectsTamperedSibling(t *testing.T) {
+	tree := testTree(t)
+	for _, level := range []int{0, 7, DefaultDepth - 1} {
+		proof, _ := tree.Prove(5)
+		proof.Siblings[level] = HashLeaf(leafData(99))
+		if err := proof.Verify(tree.Root()); !errors.Is(err, ErrInvalidProof) {
+			t.Fatalf("sibling %d tampered: err = %v, want ErrInvalidProof", level, err)
+		}
+	}
+
+	proof, _ := tree.Prove(5)
+	if err := proof.VerifyData(leafData(99), tree.Root()); !errors.Is(err, ErrInvalidProof) {
+		t.Fatalf("wrong data: err = %v, want ErrInvalidProof", err)
+	}
+	other := tree.Root()
+	other[0] ^= 1
+	if err := proof.Verify(other); !errors.Is(err, ErrRootMismatch) {
+		t.Fatalf("wrong root: err = %v, want ErrRootMismatch", err)
+	}
+}
+
+func TestVerifyRejectsWrongIndex(t *testing.T) {
+	tree := testTree(t)
+	proof, _ := tree.Prove(5)
+	for _, index := range []uint64{proof.Index - 1, proof.Index + 1, LeafIndex(DefaultDepth, 1<<31+5)} {
+		wrong := *proof
+		wrong.Index = index
+		if err := wrong.Verify(tree.Root()); !errors.Is(err, ErrInvalidProof) {
+			t.Fatalf("index %d: err = %v, want ErrInvalidProof", index, err)
+		}
+	}
+	for _, index := range []uint64{0, LeafIndex(DefaultDepth, 0) - 1, LeafIndex(DefaultDepth+1, 0)} {
+		wrong := *proof
+		wrong.Index = index
+		if err := wrong.Verify(tree.Root()); !errors.Is(err, ErrIndexOutOfRange) {
+			t.Fatalf("index %d: err = %v, want ErrIndexOutOfRange", index, err)
+		}
+	}
+}
+
+// TestDefaultHashes checks that the empty subtree hashes chain from the
+// empty leaf. The empty root itself is not compared against a rollup node.
+func TestDefaultHashes(t *testing.T) {
+	hashes := DefaultHashes(DefaultDepth)
+	if hashes[0] != HashLeaf([32]byte{}) {
+		t.Fatal("level 0 is not the empty leaf")
+	}
+	for i := 1; i <= DefaultDepth; i++ {
+		if node, _ := HashNode(hashes[i-1], hashes[i-1]); node != hashes[i] {
+			t.Fatalf("level %d does not hash level %d", i, i-1)
+		}
+	}
+	tree, _ := NewTree(DefaultDepth)
+	if tree.Root() != hashes[DefaultDepth] {
+		t.Fatal("an empty tree does not have the empty root")
+	}
+}
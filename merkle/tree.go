@@ -0,0 +1,92 @@
+package merkle
+
+import "fmt"
+
+// Tree is an in-memory sparse Merkle tree, mainly for building expected
+// roots and proofs in tests. Only nodes off the default are stored.
+type Tree struct {
+	depth    int
+	defaults []Hash
+	nodes    map[uint64]Hash
+}
+
+// NewTree creates an empty tree of the given depth
+func NewTree(depth int) (*Tree, error) {
+	if depth < 1 || depth > 63 {
+		return nil, fmt.Errorf("%w: %d", ErrInvalidTreeDepth, depth)
+	}
+	return &Tree{depth: depth, defaults: DefaultHashes(depth), nodes: make(map[uint64]Hash)}, nil
+}
+
+// Depth returns the number of levels below the root
+func (t *Tree) Depth() int {
+	return t.depth
+}
+
+// level returns the height of a global index above the leaves
+func (t *Tree) level(index uint64) int {
+	level := t.depth
+	for index > 0 {
+		index = (index - 1) / 2
+		level--
+	}
+	return level
+}
+
+func (t *Tree) node(index uint64) Hash {
+	if hash, ok := t.nodes[index]; ok {
+		return hash
+	}
+	return t.defaults[t.level(index)]
+}
+
+// Root returns the root hash
+func (t *Tree) Root() Hash {
+	return t.node(0)
+}
+
+// Set stores data at the leaf offset and rehashes the path to the root
+func (t *Tree) Set(offset uint64, data [32]byte) error {
+	return t.SetLeaf(offset, HashLeaf(data))
+}
+
+// SetLeaf stores a leaf hash at the leaf offset and rehashes the path to
+// the root
+func (t *Tree) SetLeaf(offset uint64, leaf Hash) error {
+	if offset >= uint64(1)<<uint(t.depth) {
+		return fmt.Errorf("%w: offset %d", ErrIndexOutOfRange, offset)
+	}
+	if _, err := leaf.field(); err != nil {
+		return err
+	}
+	index := LeafIndex(t.depth, offset)
+	t.nodes[index] = leaf
+	for index > 0 {
+		parent := (index - 1) / 2
+		hash, err := HashNode(t.node(2*parent+1), t.node(2*parent+2))
+		if err != nil {
+			return err
+		}
+		t.nodes[parent] = hash
+		index = parent
+	}
+	return nil
+}
+
+// Prove returns the proof of the leaf at offset
+func (t *Tree) Prove(offset uint64) (*Proof, error) {
+	if offset >= uint64(1)<<uint(t.depth) {
+		return nil, fmt.Errorf("%w: offset %d", ErrIndexOutOfRange, offset)
+	}
+	index := LeafIndex(t.depth, offset)
+	proof := &Proof{Index: index, Leaf: t.node(index), Root: t.Root()}
+	for index > 0 {
+		sibling := index + 1
+		if index%2 == 0 {
+			sibling = index - 1
+		}
+		proof.Siblings = append(proof.Siblings, t.node(sibling))
+		index = (index - 1) / 2
+	}
+	return proof, nil
+}
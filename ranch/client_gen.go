@@ -0,0 +1,132 @@
+// Code generated by zkwasm-gen. DO NOT EDIT.
+
+package ranch
+
+import (
+	"context"
+	"math/big"
+
+	"zkwasm-minirollup-rpc-go/zkwasm"
+)
+
+const clientSchemaJSON = `{
+  "commands": [
+    {"name": "InitPlayer", "id": 1},
+    {"name": "BuyElf", "id": 2, "params": [
+      {"name": "ranchId", "limb": 1},
+      {"name": "elfType", "limb": 2}
+    ]},
+    {"name": "CleanRanch", "id": 4, "params": [
+      {"name": "ranchId", "limb": 1}
+    ]},
+    {"name": "Deposit", "id": 8, "admin": true, "params": [
+      {"name": "pid1", "limb": 1},
+      {"name": "pid2", "limb": 2},
+      {"name": "ranchId", "limb": 3, "offset": 32, "bits": 32},
+      {"name": "propType", "limb": 3, "bits": 32}
+    ]},
+    {"name": "CollectCoin", "id": 11, "params": [
+      {"name": "ranchId", "limb": 1},
+      {"name": "elfId", "limb": 2}
+    ]}
+  ]
+}`
+
+// ClientSchema is the command schema Client was generated from
+var ClientSchema = func() *zkwasm.Schema {
+	schema, err := zkwasm.ParseSchema([]byte(clientSchemaJSON))
+	if err != nil {
+		panic(err)
+	}
+	return schema
+}()
+
+// Client sends the application commands signed with a player key
+// through a ZKWasmAppRpc or a ZKWasmPool
+type Client struct {
+	rpc    zkwasm.AppClient
+	prikey string
+}
+
+// NewClient creates a client sending commands through rpc signed by prikey
+func NewClient(rpc zkwasm.AppClient, prikey string) *Client {
+	return &Client{rpc: rpc, prikey: prikey}
+}
+
+// Send encodes the named command with the current nonce and waits for the
+// transaction job to finish
+func (c *Client) Send(ctx context.Context, name string, args map[string]*big.Int) (*zkwasm.TransactionResult, error) {
+	return clientSend(ctx, c.rpc, c.prikey, name, args)
+}
+
+// InitPlayer sends the InitPlayer command (id 1)
+func (c *Client) InitPlayer(ctx context.Context) (*zkwasm.TransactionResult, error) {
+	return c.Send(ctx, "InitPlayer", map[string]*big.Int{})
+}
+
+// BuyElf sends the BuyElf command (id 2)
+func (c *Client) BuyElf(ctx context.Context, ranchID uint64, elfType uint64) (*zkwasm.TransactionResult, error) {
+	return c.Send(ctx, "BuyElf", map[string]*big.Int{
+		"ranchId": new(big.Int).SetUint64(ranchID),
+		"elfType": new(big.Int).SetUint64(elfType),
+	})
+}
+
+// CleanRanch sends the CleanRanch command (id 4)
+func (c *Client) CleanRanch(ctx context.Context, ranchID uint64) (*zkwasm.TransactionResult, error) {
+	return c.Send(ctx, "CleanRanch", map[string]*big.Int{
+		"ranchId": new(big.Int).SetUint64(ranchID),
+	})
+}
+
+// CollectCoin sends the CollectCoin command (id 11)
+func (c *Client) CollectCoin(ctx context.Context, ranchID uint64, elfID uint64) (*zkwasm.TransactionResult, error) {
+	return c.Send(ctx, "CollectCoin", map[string]*big.Int{
+		"ranchId": new(big.Int).SetUint64(ranchID),
+		"elfId":   new(big.Int).SetUint64(elfID),
+	})
+}
+
+// AdminClient sends the admin-only commands signed with the admin key
+type AdminClient struct {
+	rpc    zkwasm.AppClient
+	prikey string
+}
+
+// NewAdminClient creates an admin client sending commands through rpc
+// signed by adminKey
+func NewAdminClient(rpc zkwasm.AppClient, adminKey string) *AdminClient {
+	return &AdminClient{rpc: rpc, prikey: adminKey}
+}
+
+// Send encodes the named command with the admin nonce and waits for the
+// transaction job to finish
+func (c *AdminClient) Send(ctx context.Context, name string, args map[string]*big.Int) (*zkwasm.TransactionResult, error) {
+	return clientSend(ctx, c.rpc, c.prikey, name, args)
+}
+
+// Deposit sends the admin Deposit command (id 8)
+func (c *AdminClient) Deposit(ctx context.Context, pid1 uint64, pid2 uint64, ranchID uint64, propType uint64) (*zkwasm.TransactionResult, error) {
+	return c.Send(ctx, "Deposit", map[string]*big.Int{
+		"pid1":     new(big.Int).SetUint64(pid1),
+		"pid2":     new(big.Int).SetUint64(pid2),
+		"ranchId":  new(big.Int).SetUint64(ranchID),
+		"propType": new(big.Int).SetUint64(propType),
+	})
+}
+
+func clientSend(ctx context.Context, rpc zkwasm.AppClient, prikey string, name string, args map[string]*big.Int) (*zkwasm.TransactionResult, error) {
+	cs, err := ClientSchema.Lookup(name)
+	if err != nil {
+		return nil, err
+	}
+	nonce, err := rpc.GetNonceContext(ctx, prikey)
+	if err != nil {
+		return nil, err
+	}
+	limbs, err := cs.Encode(nonce, args)
+	if err != nil {
+		return nil, err
+	}
+	return rpc.SendTransactionResultContext(ctx, limbs, prikey)
+}
@@ -0,0 +1,117 @@
+// Package ranch is the Pump Elf Ranch client built on zkwasm.AppClient.
+//
+// The command methods in client_gen.go are generated from schema.json:
+// players use Client (InitPlayer, BuyElf, CleanRanch, CollectCoin) and the
+// operator uses AdminClient for deposits.
+//
+// Player game data (ranches, elves, props and the coin balance) is kept as
+// raw JSON. Typed decoding needs the field names the server actually
+// returns from /query, and no captured response is available to confirm
+// them, so guessing a layout would decode wrong data silently. Callers that
+// know their server's layout decode it with Player.DecodeData.
+package ranch
+
+//go:generate go run zkwasm-minirollup-rpc-go/cmd/zkwasm-gen -schema schema.json -pkg ranch -out client_gen.go
+
+import (
+	"context"
+	"encoding/json"
+	"errors"
+
+	"zkwasm-minirollup-rpc-go/merkle"
+	"zkwasm-minirollup-rpc-go/zkwasm"
+)
+
+var (
+	ErrPlayerNotFound = errors.New("PlayerNotFound")
+	ErrNoPlayerData   = errors.New("NoPlayerData")
+	ErrNoProof        = errors.New("NoProof")
+)
+
+// State is the decoded data of a player query
+type State struct {
+	Player *Player `json:"player"`
+	// State holds the global game state, left undecoded
+	State json.RawMessage `json:"state"`
+	// Proof is the inclusion proof of the player record, when the server
+	// provides one
+	Proof *merkle.Proof `json:"proof,omitempty"`
+}
+
+// VerifyProof checks the inclusion proof of the state against a state
+// root obtained independently of the server. It does not tie the proof to
+// the player record: the leaf layout of the state tree is not known here.
+func (s *State) VerifyProof(root merkle.Hash) error {
+	if s.Proof == nil {
+		return ErrNoProof
+	}
+	return s.Proof.Verify(root)
+}
+
+// Player is the on-chain record of a ranch player. Only the nonce has a
+// layout confirmed by the server; the game data is left undecoded (see the
+// package documentation).
+type Player struct {
+	Nonce uint64          `json:"nonce"`
+	Data  json.RawMessage `json:"data"`
+}
+
+// DecodeData unmarshals the player game data into v
+func (p *Player) DecodeData(v interface{}) error {
+	if len(p.Data) == 0 {
+		return ErrNoPlayerData
+	}
+	return json.Unmarshal(p.Data, v)
+}
+
+// DecodeState decodes the response of ZKWasmAppRpc.QueryState
+func DecodeState(resp map[string]interface{}) (*State, error) {
+	raw, ok := resp["data"].(string)
+	if !ok {
+		return nil, errors.New("UnexpectedStateFormat")
+	}
+	var state State
+	if err := json.Unmarshal([]byte(raw), &state); err != nil {
+		return nil, err
+	}
+	return &state, nil
+}
+
+// State queries and decodes the state of the client player
+func (c *Client) State(ctx context.Context) (*State, error) {
+	resp, err := c.rpc.QueryStateContext(ctx, c.prikey)
+	if err != nil {
+		return nil, err
+	}
+	return DecodeState(resp)
+}
+
+// Player queries the client player, failing with ErrPlayerNotFound before
+// InitPlayer has been sent
+func (c *Client) Player(ctx context.Context) (*Player, error) {
+	state, err := c.State(ctx)
+	if err != nil {
+		return nil, err
+	}
+	if state.Player == nil {
+		return nil, ErrPlayerNotFound
+	}
+	return state.Player, nil
+}
+
+// Pid returns the player id of the client key, the target of deposits
+func (c *Client) Pid() (uint64, uint64) {
+	pid1, pid2 := zkwasm.GetPid(c.prikey)
+	return pid1.Uint64(), pid2.Uint64()
+}
+
+// Admin returns an admin client sharing the rpc connection of c
+func (c *Client) Admin(adminKey string) *AdminClient {
+	return NewAdminClient(c.rpc, adminKey)
+}
+
+// DepositTo deposits a prop into a ranch of the player owning client
+func (c *AdminClient) DepositTo(ctx context.Context, player *Client, ranchID, propType uint64) (*zkwasm.TransactionResult, error) {
+	pid1, pid2 := player.Pid()
+	return c.Deposit(ctx, pid1, pid2, ranchID, propType)
+}
@@ -0,0 +1,94 @@
+package ranch
+
+import (
+	"encoding/json"
+	"errors"
+	"testing"
+
+	"zkwasm-minirollup-rpc-go/merkle"
+)
+
+// stateResponse has the shape GetNoncePkx reads from /query: the player
+// record is JSON encoded in the data string
+var stateResponse = map[string]interface{}{
+	"success": true,
+	"data":    `{"player":{"nonce":7,"data":{"anything":[1,2]}},"state":{"counter":12}}`,
+}
+
+func TestDecodeState(t *testing.T) {
+	state, err := DecodeState(stateResponse)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if state.Player == nil || state.Player.Nonce != 7 {
+		t.Fatalf("player = %+v, want nonce 7", state.Player)
+	}
+	var data map[string][]int
+	if err := state.Player.DecodeData(&data); err != nil {
+		t.Fatal(err)
+	}
+	if len(data["anything"]) != 2 {
+		t.Fatalf("data = %v", data)
+	}
+	if string(state.State) != `{"counter":12}` {
+		t.Fatalf("state = %s", state.State)
+	}
+}
+
+func TestDecodeStateWithoutPlayer(t *testing.T) {
+	state, err := DecodeState(map[string]interface{}{"data": `{"player":null,"state":{}}`})
+	if err != nil {
+		t.Fatal(err)
+	}
+	if state.Player != nil {
+		t.Fatalf("player = %+v, want nil", state.Player)
+	}
+	if _, err := DecodeState(map[string]interface{}{"data": 1}); err == nil {
+		t.Fatal("non-string data decoded")
+	}
+	if err := (&Player{}).DecodeData(&struct{}{}); !errors.Is(err, ErrNoPlayerData) {
+		t.Fatalf("err = %v, want ErrNoPlayerData", err)
+	}
+}
+
+// provenState returns a state carrying the proof of a leaf in a tree that
+// holds two leaves
+func provenState(t *testing.T) (*State, merkle.Hash) {
+	t.Helper()
+	tree, err := merkle.NewTree(merkle.DefaultDepth)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if err := tree.Set(3, [32]byte{1}); err != nil {
+		t.Fatal(err)
+	}
+	if err := tree.Set(9, [32]byte{2}); err != nil {
+		t.Fatal(err)
+	}
+	proof, err := tree.Prove(3)
+	if err != nil {
+		t.Fatal(err)
+	}
+	player := &Player{Nonce: 7, Data: json.RawMessage(`{"anything":[1,2]}`)}
+	return &State{Player: player, Proof: proof}, tree.Root()
+}
+
+func TestVerifyProof(t *testing.T) {
+	state, root := provenState(t)
+	if err := state.VerifyProof(root); err != nil {
+		t.Fatal(err)
+	}
+
+	state.Proof.Siblings[0][0] ^= 1
+	if err := state.VerifyProof(root); !errors.Is(err, merkle.ErrInvalidProof) {
+		t.Fatalf("altered sibling: err = %v, want ErrInvalidProof", err)
+	}
+	state, _ = provenState(t)
+	var other merkle.Hash
+	if err := state.VerifyProof(other); !errors.Is(err, merkle.ErrRootMismatch) {
+		t.Fatalf("other root: err = %v, want ErrRootMismatch", err)
+	}
+	if err := (&State{Player: state.Player}).VerifyProof(root); !errors.Is(err, ErrNoProof) {
+		t.Fatalf("no proof: err = %v, want ErrNoProof", err)
+	}
+}
@@ -0,0 +1,48 @@
+package zkwasm
+
+import "sync"
+
+const (
+	// baseWindowBits is the width of a scalar digit in the base table
+	baseWindowBits = 4
+	baseWindows    = 256 / baseWindowBits
+)
+
+var (
+	baseTableOnce sync.Once
+	// baseTable[i][j] is j * 2^(4i) * G
+	baseTable [baseWindows][1 << baseWindowBits]*extPoint
+)
+
+func buildBaseTable() {
+	window := PointBase().extended()
+	for i := 0; i < baseWindows; i++ {
+		baseTable[i][0] = window.identity()
+		for j := 1; j < 1<<baseWindowBits; j++ {
+			baseTable[i][j] = baseTable[i][j-1].add(window)
+		}
+		for j, entry := range baseTable[i] {
+			baseTableWords[i][j] = entry.words()
+		}
+		// the next window starts at 16 times this one
+		window = baseTable[i][(1<<baseWindowBits)-1].add(window)
+	}
+}
+
+// PointBaseMul computes k * G for the base point G. It looks up one
+// precomputed multiple per 4-bit digit of k, trading a table built on
+// first use for all of the doublings of Mul.
+func PointBaseMul(k *Field) *Point {
+	baseTableOnce.Do(buildBaseTable)
+	scalar := fieldArith.reduce(&k.m)
+	result := baseTable[0][0]
+	for i := 0; i < baseWindows; i++ {
+		digit := (scalar[i/16] >> (4 * (i % 16))) & (1<<baseWindowBits - 1)
+		if digit != 0 {
+			result = result.add(baseTable[i][digit])
+		}
+	}
+	// multiples of G are on the curve, so Z is never zero
+	product, _ := result.affine()
+	return product
+}
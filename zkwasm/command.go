@@ -0,0 +1,96 @@
+package zkwasm
+
+import (
+	"errors"
+	"fmt"
+	"math/big"
+)
+
+var ErrCommandFieldOverflow = errors.New("CommandFieldOverflow")
+
+// Bit widths of the fields packed into the first transaction limb
+const (
+	CommandIDBits       = 8
+	CommandObjIndexBits = 8
+	CommandNonceBits    = 48
+)
+
+// Command is the first limb of a zkWasm transaction. Following the zkWasm
+// convention it is encoded as nonce<<16 + objindex<<8 + command id, where
+// the object index doubles as the argument count in newer applications.
+type Command struct {
+	Nonce    uint64
+	ID       uint64
+	ObjIndex uint64
+}
+
+// NewCommand builds a Command from big integers, rejecting values that do
+// not fit their field
+func NewCommand(nonce, command, objindex *big.Int) (*Command, error) {
+	fields := []struct {
+		name  string
+		value *big.Int
+		bits  uint
+	}{
+		{"nonce", nonce, CommandNonceBits},
+		{"command", command, CommandIDBits},
+		{"objindex", objindex, CommandObjIndexBits},
+	}
+	for _, f := range fields {
+		if f.value == nil || f.value.Sign() < 0 || f.value.BitLen() > int(f.bits) {
+			return nil, fmt.Errorf("%w: %s does not fit in %d bits", ErrCommandFieldOverflow, f.name, f.bits)
+		}
+	}
+	return &Command{
+		Nonce:    nonce.Uint64(),
+		ID:       command.Uint64(),
+		ObjIndex: objindex.Uint64(),
+	}, nil
+}
+
+// Validate checks that every field fits its bit width
+func (c *Command) Validate() error {
+	if c.Nonce>>CommandNonceBits != 0 {
+		return fmt.Errorf("%w: nonce does not fit in %d bits", ErrCommandFieldOverflow, CommandNonceBits)
+	}
+	if c.ID>>CommandIDBits != 0 {
+		return fmt.Errorf("%w: command does not fit in %d bits", ErrCommandFieldOverflow, CommandIDBits)
+	}
+	if c.ObjIndex>>CommandObjIndexBits != 0 {
+		return fmt.Errorf("%w: objindex does not fit in %d bits", ErrCommandFieldOverflow, CommandObjIndexBits)
+	}
+	return nil
+}
+
+// Encode returns the first transaction limb for the command
+func (c *Command) Encode() (*big.Int, error) {
+	if err := c.Validate(); err != nil {
+		return nil, err
+	}
+	return c.limb(), nil
+}
+
+func (c *Command) limb() *big.Int {
+	nonce := c.Nonce & (1<<CommandNonceBits - 1)
+	objindex := c.ObjIndex & (1<<CommandObjIndexBits - 1)
+	command := c.ID & (1<<CommandIDBits - 1)
+	return new(big.Int).SetUint64(nonce<<16 | objindex<<8 | command)
+}
+
+// DecodeCommand splits a first transaction limb back into its fields
+func DecodeCommand(limb *big.Int) (*Command, error) {
+	if limb == nil || limb.Sign() < 0 || limb.BitLen() > 64 {
+		return nil, fmt.Errorf("%w: command limb is not a u64", ErrCommandFieldOverflow)
+	}
+	v := limb.Uint64()
+	return &Command{
+		Nonce:    v >> 16,
+		ID:       v & 0xff,
+		ObjIndex: (v >> 8) & 0xff,
+	}, nil
+}
+
+// String returns a readable form of the command fields
+func (c *Command) String() string {
+	return fmt.Sprintf("Command(nonce: %d, id: %d, objindex: %d)", c.Nonce, c.ID, c.ObjIndex)
+}
@@ -0,0 +1,50 @@
+package zkwasm
+
+import (
+	"errors"
+	"math/big"
+	"testing"
+)
+
+func TestCreateCommand(t *testing.T) {
+	rpc := NewZKWasmAppRpc("http://localhost")
+	limb, err := rpc.CreateCommand(big.NewInt(3), big.NewInt(2), big.NewInt(1))
+	if err != nil {
+		t.Fatal(err)
+	}
+	if want := big.NewInt(3<<16 | 1<<8 | 2); limb.Cmp(want) != 0 {
+		t.Fatalf("limb = %s, want %s", limb, want)
+	}
+
+	wide := new(big.Int).Lsh(big.NewInt(1), 64)
+	cases := []struct {
+		name                     string
+		nonce, command, objindex *big.Int
+	}{
+		{"nonce", new(big.Int).Lsh(big.NewInt(1), CommandNonceBits), big.NewInt(0), big.NewInt(0)},
+		{"nonce beyond u64", wide, big.NewInt(0), big.NewInt(0)},
+		{"command", big.NewInt(0), big.NewInt(256), big.NewInt(0)},
+		{"objindex", big.NewInt(0), big.NewInt(0), big.NewInt(256)},
+		{"negative", big.NewInt(-1), big.NewInt(0), big.NewInt(0)},
+	}
+	for _, c := range cases {
+		if _, err := rpc.CreateCommand(c.nonce, c.command, c.objindex); !errors.Is(err, ErrCommandFieldOverflow) {
+			t.Errorf("%s: err = %v, want ErrCommandFieldOverflow", c.name, err)
+		}
+	}
+}
+
+func TestDecodeCommandRoundTrip(t *testing.T) {
+	cmd := &Command{Nonce: 1<<CommandNonceBits - 1, ID: 0xab, ObjIndex: 0xcd}
+	limb, err := cmd.Encode()
+	if err != nil {
+		t.Fatal(err)
+	}
+	decoded, err := DecodeCommand(limb)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if *decoded != *cmd {
+		t.Fatalf("decoded %v, want %v", decoded, cmd)
+	}
+}
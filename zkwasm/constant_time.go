@@ -0,0 +1,90 @@
+package zkwasm
+
+import (
+	"crypto/subtle"
+)
+
+// Secret scalars go through fixed-width limbs: every digit or bit is
+// processed, table entries are chosen by masking rather than by index,
+// and no step is skipped for a zero digit. Public scalars, as in
+// VerifySign, keep the faster variable-time Mul and PointBaseMul.
+//
+// This relies on the point formulas running on the constant-time
+// Montgomery arithmetic of Field; before Field moved off math/big the
+// ladder hid the scalar bits but the field operations beneath it did not.
+// The final inversion raises Z to the public p-2. Building the scalar
+// with NewField from a big.Int is variable time and should happen once
+// per key.
+
+// extWords is an extPoint as the Montgomery limbs of X, Y, Z and T
+type extWords [16]uint64
+
+var baseTableWords [baseWindows][1 << baseWindowBits]extWords
+
+func (p *extPoint) words() extWords {
+	var out extWords
+	copy(out[0:4], p.x.m[:])
+	copy(out[4:8], p.y.m[:])
+	copy(out[8:12], p.z.m[:])
+	copy(out[12:16], p.t.m[:])
+	return out
+}
+
+func (w *extWords) point() *extPoint {
+	p := &extPoint{x: &Field{}, y: &Field{}, z: &Field{}, t: &Field{}}
+	copy(p.x.m[:], w[0:4])
+	copy(p.y.m[:], w[4:8])
+	copy(p.z.m[:], w[8:12])
+	copy(p.t.m[:], w[12:16])
+	return p
+}
+
+// PointBaseMulSecret computes k * G for a secret k, such as a private key
+// or signing nonce, in constant time with respect to k
+func PointBaseMulSecret(k *Field) *Point {
+	baseTableOnce.Do(buildBaseTable)
+	scalar := fieldArith.reduce(&k.m)
+	result := baseTable[0][0]
+	var entry extWords
+	for i := 0; i < baseWindows; i++ {
+		digit := int32(scalar[i/16]>>(4*(i%16))) & (1<<baseWindowBits - 1)
+		for j := range baseTableWords[i] {
+			mask := -uint64(subtle.ConstantTimeEq(int32(j), digit))
+			for n := range entry {
+				entry[n] = (entry[n] &^ mask) | (baseTableWords[i][j][n] & mask)
+			}
+		}
+		result = result.add(entry.point())
+	}
+	// multiples of G are on the curve, so Z is never zero
+	product, _ := result.affine()
+	return product
+}
+
+// MulSecret performs scalar multiplication (k * P) for a secret k with a
+// Montgomery ladder over all 256 bits of k, returning nil like Add
+func (p *Point) MulSecret(k *Field) *Point {
+	scalar := fieldArith.reduce(&k.m)
+	r0 := p.extended().identity().words()
+	r1 := p.extended().words()
+	for i := 255; i >= 0; i-- {
+		bit := (scalar[i/64] >> (i % 64)) & 1
+		constantTimeSwap(bit, &r0, &r1)
+		a, b := r0.point(), r1.point()
+		r1 = a.add(b).words()
+		r0 = a.double().words()
+		constantTimeSwap(bit, &r0, &r1)
+	}
+	product, _ := r0.point().affine()
+	return product
+}
+
+// constantTimeSwap swaps a and b when swap is 1 and leaves them when 0
+func constantTimeSwap(swap uint64, a, b *extWords) {
+	mask := -swap
+	for i := range a {
+		t := mask & (a[i] ^ b[i])
+		a[i] ^= t
+		b[i] ^= t
+	}
+}
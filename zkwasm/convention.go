@@ -1,60 +1,28 @@
 package zkwasm
 
 import (
+	"context"
 	"encoding/hex"
 	"encoding/json"
+	"errors"
 	"fmt"
 	"math/big"
-	"strconv"
+	"sort"
 	"strings"
 )
 
+var (
+	ErrInvalidPid             = errors.New("InvalidPid")
+	ErrTooManyArguments       = errors.New("TooManyArguments")
+	ErrCommandNotConfigured   = errors.New("CommandNotConfigured")
+	ErrUnexpectedStateFormat  = errors.New("UnexpectedStateFormat")
+	ErrUnexpectedConfigFormat = errors.New("UnexpectedConfigFormat")
+)
+
 func bytesToHex(bytes []byte) string {
 	return hex.EncodeToString(bytes)
 }
 
-func bytesToDecimal(bytes []byte) string {
-	var sb strings.Builder
-	for _, b := range bytes {
-		sb.WriteString(fmt.Sprintf("%02d", b))
-	}
-	return sb.String()
-}
-
-func composeWithdrawParams(address string, amount *big.Int) ([]*big.Int, error) {
-	addressBytes, err := hex.DecodeString(address)
-	if err != nil {
-		return nil, err
-	}
-	firstLimb := new(big.Int).SetBytes(reverseBytes(addressBytes[:4]))
-	sndLimb := new(big.Int).SetBytes(reverseBytes(addressBytes[4:12]))
-	thirdLimb := new(big.Int).SetBytes(reverseBytes(addressBytes[12:20]))
-	one := new(big.Int).Add(new(big.Int).Lsh(firstLimb, 32), amount)
-	return []*big.Int{one, sndLimb, thirdLimb}, nil
-}
-
-func decodeWithdraw(txdata []byte) ([]map[string]interface{}, error) {
-	var result []map[string]interface{}
-	if len(txdata) > 1 {
-		for i := 0; i < len(txdata); i += 32 {
-			extra := txdata[i : i+4]
-			address := txdata[i+4 : i+24]
-			amount := txdata[i+24 : i+32]
-			amountInWei, err := strconv.ParseInt(bytesToDecimal(amount), 10, 64)
-			if err != nil {
-				return nil, err
-			}
-			result = append(result, map[string]interface{}{
-				"op":      extra[0],
-				"index":   extra[1],
-				"address": "0x" + bytesToHex(address),
-				"amount":  amountInWei,
-			})
-		}
-	}
-	return result, nil
-}
-
 func reverseBytes(bytes []byte) []byte {
 	for i, j := 0, len(bytes)-1; i < j; i, j = i+1, j-1 {
 		bytes[i], bytes[j] = bytes[j], bytes[i]
@@ -62,14 +30,21 @@ func reverseBytes(bytes []byte) []byte {
 	return bytes
 }
 
+// PlayerConvention sends commands on behalf of a single key following the
+// zkWasm mini-rollup conventions. Deposit uses the admin pattern: the
+// processing key is the admin key and signs a command carrying the target
+// pid. Withdraw is sent by the player itself, so withdrawals need a
+// convention holding the player key.
 type PlayerConvention struct {
 	processingKey   string
-	rpc             *ZKWasmAppRpc
+	rpc             AppClient
 	commandDeposit  *big.Int
 	commandWithdraw *big.Int
 }
 
-func NewPlayerConvention(key string, rpc *ZKWasmAppRpc, commandDeposit, commandWithdraw *big.Int) *PlayerConvention {
+// NewPlayerConvention creates a PlayerConvention sending through rpc, a
+// ZKWasmAppRpc or a ZKWasmPool, signed by key
+func NewPlayerConvention(key string, rpc AppClient, commandDeposit, commandWithdraw *big.Int) *PlayerConvention {
 	return &PlayerConvention{
 		processingKey:   key,
 		rpc:             rpc,
@@ -78,66 +53,280 @@ func NewPlayerConvention(key string, rpc *ZKWasmAppRpc, commandDeposit, commandW
 	}
 }
 
-func (pc *PlayerConvention) createCommand(nonce, command, objindex *big.Int) *big.Int {
-	bigNonce0 := new(big.Int).Lsh(nonce, 16) // cmd[1] << 16
-	bigCmd0 := new(big.Int).Lsh(command, 8)  // cmd[2] << 8
-	cmd := new(big.Int).Add(bigNonce0, bigCmd0)
-	cmd = cmd.Add(cmd, objindex)
-	return cmd
+// NewPlayerConventionFromConfig creates a PlayerConvention whose deposit
+// and withdraw command ids are discovered from the /config output (see
+// DiscoverCommandIDs). Commands the config does not list are left unset
+// and fail with ErrCommandNotConfigured when used.
+func NewPlayerConventionFromConfig(ctx context.Context, key string, rpc AppClient) (*PlayerConvention, error) {
+	config, err := rpc.QueryConfigContext(ctx)
+	if err != nil {
+		return nil, err
+	}
+	ids, err := DiscoverCommandIDs(config)
+	if err != nil {
+		return nil, err
+	}
+	pc := NewPlayerConvention(key, rpc, nil, nil)
+	if id, ok := lookupCommandID(ids, "deposit"); ok {
+		pc.commandDeposit = new(big.Int).SetUint64(id)
+	}
+	if id, ok := lookupCommandID(ids, "withdraw"); ok {
+		pc.commandWithdraw = new(big.Int).SetUint64(id)
+	}
+	return pc, nil
 }
 
-func (pc *PlayerConvention) getConfig() (map[string]interface{}, error) {
-	return pc.rpc.QueryConfig()
+// DiscoverCommandIDs extracts command ids from /config output. The server
+// has no standard place for them, so this reads the convention of a
+// "commands" object mapping names to numbers, looked up at the top level
+// and inside "data" (either an object or a JSON encoded string). Configs
+// without one fail with ErrUnexpectedConfigFormat; pass the ids to
+// NewPlayerConvention instead. Names are normalised to lower case without
+// "_", "-", "cmd" or "command", and names that normalise alike must agree.
+func DiscoverCommandIDs(config map[string]interface{}) (map[string]uint64, error) {
+	ids := make(map[string]uint64)
+	origins := make(map[string]string)
+	scopes := []map[string]interface{}{config}
+	switch data := config["data"].(type) {
+	case map[string]interface{}:
+		scopes = append(scopes, data)
+	case string:
+		var parsed map[string]interface{}
+		if err := json.Unmarshal([]byte(data), &parsed); err == nil {
+			scopes = append(scopes, parsed)
+		}
+	}
+	found := false
+	for _, scope := range scopes {
+		commands, ok := scope["commands"]
+		if !ok {
+			continue
+		}
+		commandMap, ok := commands.(map[string]interface{})
+		if !ok {
+			return nil, fmt.Errorf("%w: commands is not an object", ErrUnexpectedConfigFormat)
+		}
+		found = true
+		names := make([]string, 0, len(commandMap))
+		for name := range commandMap {
+			names = append(names, name)
+		}
+		sort.Strings(names)
+		for _, name := range names {
+			value := commandMap[name]
+			id, ok := value.(float64)
+			if !ok || id < 0 || id >= 1<<CommandIDBits || id != float64(uint64(id)) {
+				return nil, fmt.Errorf("%w: command %s has invalid id %v", ErrUnexpectedConfigFormat, name, value)
+			}
+			key := normalizeCommandName(name)
+			if other, ok := origins[key]; ok && (other != name || ids[key] != uint64(id)) {
+				return nil, fmt.Errorf("%w: commands %s and %s both name %s", ErrUnexpectedConfigFormat, other, name, key)
+			}
+			ids[key] = uint64(id)
+			origins[key] = name
+		}
+	}
+	if !found {
+		return nil, fmt.Errorf("%w: no commands object", ErrUnexpectedConfigFormat)
+	}
+	return ids, nil
+}
+
+func normalizeCommandName(name string) string {
+	name = strings.ToLower(name)
+	name = strings.NewReplacer("_", "", "-", "").Replace(name)
+	for _, affix := range []string{"command", "cmd"} {
+		name = strings.TrimPrefix(name, affix)
+		name = strings.TrimSuffix(name, affix)
+	}
+	return name
 }
 
-func (pc *PlayerConvention) getState() (map[string]interface{}, error) {
-	state, err := pc.rpc.QueryState(pc.processingKey)
+func lookupCommandID(ids map[string]uint64, name string) (uint64, bool) {
+	id, ok := ids[normalizeCommandName(name)]
+	return id, ok
+}
+
+func (pc *PlayerConvention) createCommand(nonce, command, objindex *big.Int) (*big.Int, error) {
+	if command == nil {
+		return nil, ErrCommandNotConfigured
+	}
+	cmd, err := NewCommand(nonce, command, objindex)
 	if err != nil {
 		return nil, err
 	}
+	return cmd.Encode()
+}
+
+// GetConfig returns the raw /config output of the application
+func (pc *PlayerConvention) GetConfig(ctx context.Context) (map[string]interface{}, error) {
+	return pc.rpc.QueryConfigContext(ctx)
+}
+
+// GetState returns the decoded state data of the processing key
+func (pc *PlayerConvention) GetState(ctx context.Context) (map[string]interface{}, error) {
+	state, err := pc.rpc.QueryStateContext(ctx, pc.processingKey)
+	if err != nil {
+		return nil, err
+	}
+	return DecodeStateData(state)
+}
+
+// DecodeStateData decodes the JSON encoded data field of a /query response
+func DecodeStateData(state map[string]interface{}) (map[string]interface{}, error) {
+	raw, ok := state["data"].(string)
+	if !ok {
+		return nil, ErrUnexpectedStateFormat
+	}
 	var parsedState map[string]interface{}
-	if err := json.Unmarshal([]byte(state["data"].(string)), &parsedState); err != nil {
+	if err := json.Unmarshal([]byte(raw), &parsedState); err != nil {
 		return nil, err
 	}
 	return parsedState, nil
 }
 
-func (pc *PlayerConvention) getNonce() (*big.Int, error) {
-	data, err := pc.getState()
+// decodeNonce reads the player nonce from a /query response, zero when the
+// player has not been created yet
+func decodeNonce(state map[string]interface{}) (*big.Int, error) {
+	raw, ok := state["data"].(string)
+	if !ok {
+		return nil, ErrUnexpectedStateFormat
+	}
+	var data struct {
+		Player json.RawMessage `json:"player"`
+	}
+	if err := json.Unmarshal([]byte(raw), &data); err != nil {
+		return nil, fmt.Errorf("%w: %v", ErrUnexpectedStateFormat, err)
+	}
+	if len(data.Player) == 0 || string(data.Player) == "null" {
+		return big.NewInt(0), nil
+	}
+	var player struct {
+		Nonce *json.Number `json:"nonce"`
+	}
+	if err := json.Unmarshal(data.Player, &player); err != nil {
+		return nil, fmt.Errorf("%w: player: %v", ErrUnexpectedStateFormat, err)
+	}
+	if player.Nonce == nil {
+		return nil, fmt.Errorf("%w: player nonce is missing", ErrUnexpectedStateFormat)
+	}
+	nonce, ok := new(big.Int).SetString(player.Nonce.String(), 10)
+	if !ok || nonce.Sign() < 0 || nonce.BitLen() > 64 {
+		return nil, fmt.Errorf("%w: player nonce %s is not a u64", ErrUnexpectedStateFormat, player.Nonce)
+	}
+	return nonce, nil
+}
+
+// GetNonce returns the nonce of the processing key, zero when the player
+// has not been created yet
+func (pc *PlayerConvention) GetNonce(ctx context.Context) (*big.Int, error) {
+	return pc.rpc.GetNonceContext(ctx, pc.processingKey)
+}
+
+// Send signs command with the current nonce and up to three argument limbs,
+// then waits for the transaction job. Missing arguments are sent as zero.
+func (pc *PlayerConvention) Send(ctx context.Context, command, objIndex *big.Int, args ...*big.Int) (*TransactionResult, error) {
+	if len(args) > 3 {
+		return nil, fmt.Errorf("%w: a command carries at most 3 arguments, got %d", ErrTooManyArguments, len(args))
+	}
+	nonce, err := pc.GetNonce(ctx)
 	if err != nil {
 		return nil, err
 	}
-	player := data["player"].(map[string]interface{})
-	nonce := big.NewInt(int64(player["nonce"].(float64)))
-	return nonce, nil
+	cmd, err := pc.createCommand(nonce, command, objIndex)
+	if err != nil {
+		return nil, err
+	}
+	limbs := [4]*big.Int{cmd, big.NewInt(0), big.NewInt(0), big.NewInt(0)}
+	for i, arg := range args {
+		if arg == nil || arg.Sign() < 0 || arg.BitLen() > 64 {
+			return nil, fmt.Errorf("%w: argument %d is not a u64", ErrInvalidArgument, i)
+		}
+		limbs[i+1] = arg
+	}
+	return pc.rpc.SendTransactionResultContext(ctx, limbs, pc.processingKey)
 }
 
+// ValidatePid checks that pid1 and pid2 are u64 limbs of a real player id
+func ValidatePid(pid1, pid2 *big.Int) error {
+	for i, pid := range []*big.Int{pid1, pid2} {
+		if pid == nil || pid.Sign() < 0 || pid.BitLen() > 64 {
+			return fmt.Errorf("%w: pid%d is not a u64", ErrInvalidPid, i+1)
+		}
+	}
+	if pid1.Sign() == 0 && pid2.Sign() == 0 {
+		return fmt.Errorf("%w: pid is zero", ErrInvalidPid)
+	}
+	return nil
+}
+
+// Deposit credits amount to the player identified by pid1 and pid2. The
+// processing key must be the application admin key.
 func (pc *PlayerConvention) Deposit(pid1, pid2, amount *big.Int) (string, error) {
-	nonce, err := pc.getNonce()
+	result, err := pc.DepositContext(context.Background(), pid1, pid2, amount)
 	if err != nil {
 		return "", err
 	}
-	return pc.rpc.SendTransaction([4]*big.Int{
-		pc.createCommand(nonce, pc.commandDeposit, big.NewInt(0)),
-		pid1,
-		pid2,
-		amount,
-	}, pc.processingKey)
+	return result.ReturnValue, nil
+}
+
+// DepositContext is Deposit with a context, returning the job result
+func (pc *PlayerConvention) DepositContext(ctx context.Context, pid1, pid2, amount *big.Int) (*TransactionResult, error) {
+	if err := ValidatePid(pid1, pid2); err != nil {
+		return nil, err
+	}
+	return pc.Send(ctx, pc.commandDeposit, big.NewInt(0), pid1, pid2, amount)
+}
+
+// DepositToKey credits amount to the player owning prikey
+func (pc *PlayerConvention) DepositToKey(ctx context.Context, prikey string, amount *big.Int) (*TransactionResult, error) {
+	pid1, pid2 := GetPid(prikey)
+	return pc.DepositContext(ctx, pid1, pid2, amount)
 }
 
+// WithdrawRewards withdraws amount from the processing key's player to the
+// L1 address
 func (pc *PlayerConvention) WithdrawRewards(address string, amount *big.Int) (string, error) {
-	nonce, err := pc.getNonce()
+	submitted, err := pc.SubmitWithdrawRewards(address, amount)
 	if err != nil {
 		return "", err
 	}
-	params, err := composeWithdrawParams(address, amount)
+	return submitted.ReturnValue, nil
+}
+
+// TrackedWithdrawal is a withdraw command accepted by the rollup, kept for
+// reconciliation against settlement batches
+type TrackedWithdrawal struct {
+	SubmittedWithdrawal
+	ReturnValue string
+}
+
+// SubmitWithdrawRewards sends a withdraw command like WithdrawRewards and
+// returns the player pid and job id needed to reconcile the payout. The
+// processing key is the withdrawing player.
+func (pc *PlayerConvention) SubmitWithdrawRewards(address string, amount *big.Int) (*TrackedWithdrawal, error) {
+	return pc.SubmitWithdrawRewardsContext(context.Background(), address, amount)
+}
+
+// SubmitWithdrawRewardsContext is SubmitWithdrawRewards with a context
+func (pc *PlayerConvention) SubmitWithdrawRewardsContext(ctx context.Context, address string, amount *big.Int) (*TrackedWithdrawal, error) {
+	params, err := ComposeWithdrawParams(address, amount)
 	if err != nil {
-		return "", err
+		return nil, err
+	}
+	result, err := pc.Send(ctx, pc.commandWithdraw, big.NewInt(0), params[0], params[1], params[2])
+	if err != nil {
+		return nil, err
 	}
-	return pc.rpc.SendTransaction([4]*big.Int{
-		pc.createCommand(nonce, pc.commandWithdraw, big.NewInt(0)),
-		params[0],
-		params[1],
-		params[2],
-	}, pc.processingKey)
+	pid1, pid2 := GetPid(pc.processingKey)
+	return &TrackedWithdrawal{
+		SubmittedWithdrawal: SubmittedWithdrawal{
+			Pid1:    pid1,
+			Pid2:    pid2,
+			JobID:   result.JobID,
+			Address: address,
+			Amount:  amount,
+		},
+		ReturnValue: result.ReturnValue,
+	}, nil
 }
@@ -0,0 +1,194 @@
+package zkwasm
+
+import (
+	"context"
+	"errors"
+	"math/big"
+	"testing"
+)
+
+func TestDecodeNonce(t *testing.T) {
+	cases := []struct {
+		data string
+		want uint64
+	}{
+		{`{}`, 0},
+		{`{"player": null}`, 0},
+		{`{"player": {"nonce": 5, "data": []}}`, 5},
+		{`{"player": {"nonce": 18446744073709551615}}`, 1<<64 - 1},
+	}
+	for _, c := range cases {
+		nonce, err := decodeNonce(map[string]interface{}{"data": c.data})
+		if err != nil {
+			t.Fatalf("%s: %v", c.data, err)
+		}
+		if nonce.Uint64() != c.want || !nonce.IsUint64() {
+			t.Fatalf("%s: nonce = %s, want %d", c.data, nonce, c.want)
+		}
+	}
+
+	for _, state := range []map[string]interface{}{
+		{},
+		{"data": 1},
+		{"data": `{"player": `},
+		{"data": `{"player": "alice"}`},
+		{"data": `{"player": {}}`},
+		{"data": `{"player": {"nonce": -1}}`},
+		{"data": `{"player": {"nonce": 1.5}}`},
+		{"data": `{"player": {"nonce": 18446744073709551616}}`},
+	} {
+		if _, err := decodeNonce(state); !errors.Is(err, ErrUnexpectedStateFormat) {
+			t.Errorf("%v: err = %v, want ErrUnexpectedStateFormat", state, err)
+		}
+	}
+}
+
+func TestPlayerConventionSend(t *testing.T) {
+	fake, server := newFakeRollup(t)
+	rpc := NewZKWasmAppRpc(server.URL)
+	pc := NewPlayerConvention("1234", rpc, nil, nil)
+	ctx := context.Background()
+
+	for nonce := uint64(0); nonce < 2; nonce++ {
+		if _, err := pc.Send(ctx, big.NewInt(3), big.NewInt(1), big.NewInt(7), big.NewInt(8)); err != nil {
+			t.Fatal(err)
+		}
+		msg := fake.messages[nonce]
+		limbs := make([]uint64, 4)
+		for i := range limbs {
+			limbs[i] = new(big.Int).Rsh(msg, uint(64*i)).Uint64()
+		}
+		want := []uint64{(&Command{Nonce: nonce, ID: 3, ObjIndex: 1}).limb().Uint64(), 7, 8, 0}
+		for i := range limbs {
+			if limbs[i] != want[i] {
+				t.Fatalf("send %d limbs = %v, want %v", nonce, limbs, want)
+			}
+		}
+	}
+
+	if _, err := pc.Send(ctx, big.NewInt(3), big.NewInt(0), big.NewInt(1), big.NewInt(2), big.NewInt(3), big.NewInt(4)); !errors.Is(err, ErrTooManyArguments) {
+		t.Fatalf("too many arguments err = %v", err)
+	}
+	if _, err := pc.Send(ctx, big.NewInt(3), big.NewInt(0), big.NewInt(-1)); !errors.Is(err, ErrInvalidArgument) {
+		t.Fatalf("negative argument err = %v", err)
+	}
+	if _, err := pc.Send(ctx, big.NewInt(3), big.NewInt(0), new(big.Int).Lsh(big.NewInt(1), 64)); !errors.Is(err, ErrInvalidArgument) {
+		t.Fatalf("wide argument err = %v", err)
+	}
+	if _, err := pc.Send(ctx, nil, big.NewInt(0)); !errors.Is(err, ErrCommandNotConfigured) {
+		t.Fatalf("unset command err = %v", err)
+	}
+	if fake.appliedCount() != 2 {
+		t.Fatalf("applied %d commands, want 2", fake.appliedCount())
+	}
+}
+
+func TestDiscoverCommandIDs(t *testing.T) {
+	cases := []struct {
+		name   string
+		config map[string]interface{}
+		want   map[string]uint64
+	}{
+		{
+			"top level",
+			map[string]interface{}{"commands": map[string]interface{}{"CMD_DEPOSIT": 8.0, "withdraw_command": 9.0}},
+			map[string]uint64{"deposit": 8, "withdraw": 9},
+		},
+		{
+			"encoded data",
+			map[string]interface{}{"data": `{"commands": {"Deposit": 8}}`},
+			map[string]uint64{"deposit": 8},
+		},
+		{
+			"data object",
+			map[string]interface{}{"data": map[string]interface{}{"commands": map[string]interface{}{"buy-elf": 2.0}}},
+			map[string]uint64{"buyelf": 2},
+		},
+		{
+			"both scopes agreeing",
+			map[string]interface{}{"commands": map[string]interface{}{"deposit": 8.0}, "data": `{"commands": {"deposit": 8}}`},
+			map[string]uint64{"deposit": 8},
+		},
+	}
+	for _, c := range cases {
+		ids, err := DiscoverCommandIDs(c.config)
+		if err != nil {
+			t.Fatalf("%s: %v", c.name, err)
+		}
+		if len(ids) != len(c.want) {
+			t.Fatalf("%s: ids = %v, want %v", c.name, ids, c.want)
+		}
+		for name, id := range c.want {
+			if ids[name] != id {
+				t.Fatalf("%s: ids = %v, want %v", c.name, ids, c.want)
+			}
+		}
+	}
+
+	for name, config := range map[string]map[string]interface{}{
+		"no commands":       {"data": `{"name": "ranch"}`},
+		"commands array":    {"commands": []interface{}{8.0}},
+		"fractional id":     {"commands": map[string]interface{}{"deposit": 8.5}},
+		"wide id":           {"commands": map[string]interface{}{"deposit": 256.0}},
+		"string id":         {"commands": map[string]interface{}{"deposit": "8"}},
+		"colliding names":   {"commands": map[string]interface{}{"deposit": 8.0, "CMD_DEPOSIT": 9.0}},
+		"same name, new id": {"commands": map[string]interface{}{"deposit": 8.0}, "data": `{"commands": {"deposit": 9}}`},
+	} {
+		if _, err := DiscoverCommandIDs(config); !errors.Is(err, ErrUnexpectedConfigFormat) {
+			t.Errorf("%s: err = %v, want ErrUnexpectedConfigFormat", name, err)
+		}
+	}
+}
+
+func TestValidatePid(t *testing.T) {
+	wide := new(big.Int).Lsh(big.NewInt(1), 64)
+	for _, pid := range [][2]*big.Int{
+		{nil, big.NewInt(1)},
+		{big.NewInt(1), nil},
+		{big.NewInt(-1), big.NewInt(1)},
+		{big.NewInt(1), wide},
+		{big.NewInt(0), big.NewInt(0)},
+	} {
+		if err := ValidatePid(pid[0], pid[1]); !errors.Is(err, ErrInvalidPid) {
+			t.Errorf("ValidatePid(%v, %v) = %v, want ErrInvalidPid", pid[0], pid[1], err)
+		}
+	}
+	pid1, pid2 := GetPid("1234")
+	if err := ValidatePid(pid1, pid2); err != nil {
+		t.Fatal(err)
+	}
+	if err := ValidatePid(big.NewInt(0), new(big.Int).SetUint64(1<<64-1)); err != nil {
+		t.Fatal(err)
+	}
+}
+
+func TestNewPlayerConventionFromConfig(t *testing.T) {
+	fake, server := newFakeRollup(t)
+	fake.config = map[string]interface{}{"commands": map[string]interface{}{"deposit": 8}}
+	rpc := NewZKWasmAppRpc(server.URL)
+	ctx := context.Background()
+
+	pc, err := NewPlayerConventionFromConfig(ctx, "1234", rpc)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if _, err := pc.DepositToKey(ctx, "5678", big.NewInt(10)); err != nil {
+		t.Fatal(err)
+	}
+	cmd, err := DecodeCommand(new(big.Int).SetUint64(fake.applied[0]))
+	if err != nil {
+		t.Fatal(err)
+	}
+	if cmd.ID != 8 {
+		t.Fatalf("deposit sent command %d, want 8", cmd.ID)
+	}
+	// the config lists no withdraw command
+	if _, err := pc.SubmitWithdrawRewardsContext(ctx, "0x5aAeb6053F3E94C9b9A09f33669435E7Ef1BeAed", big.NewInt(1)); !errors.Is(err, ErrCommandNotConfigured) {
+		t.Fatalf("withdraw err = %v, want ErrCommandNotConfigured", err)
+	}
+
+	fake.config = map[string]interface{}{"name": "ranch"}
+	if _, err := NewPlayerConventionFromConfig(ctx, "1234", rpc); !errors.Is(err, ErrUnexpectedConfigFormat) {
+		t.Fatalf("config without commands err = %v", err)
+	}
+}
@@ -4,26 +4,27 @@ import (
 	"math/big"
 )
 
+// curveFieldArith is arithmetic modulo the order of the curve subgroup
+var curveFieldArith = newMontgomery("2736030358979909402780800718157159386076813972158567259200215660948447373041")
+
+// CurveField is a scalar modulo the order of the curve subgroup
 type CurveField struct {
-	v       *big.Int
-	modulus *big.Int
+	// m is the value in Montgomery form
+	m limbs
 }
 
 func NewCurveField(v interface{}) *CurveField {
-	modulus := new(big.Int)
-	modulus.SetString("2736030358979909402780800718157159386076813972158567259200215660948447373041", 10)
-
 	var value *big.Int
 	switch v := v.(type) {
 	case *Field:
-		value = v.v
+		value = v.BigInt()
 	case string:
 		value = new(big.Int)
 		value.SetString(v, 10)
 	case int:
 		value = big.NewInt(int64(v))
 	case uint64:
-		value = big.NewInt(int64(v))
+		value = new(big.Int).SetUint64(v)
 	case int64:
 		value = big.NewInt(v)
 	case *big.Int:
@@ -32,62 +33,121 @@ func NewCurveField(v interface{}) *CurveField {
 		panic("v must be an int, string, uint64,int64, or Field")
 	}
 
-	value.Mod(value, modulus)
-	return &CurveField{v: value, modulus: modulus}
+	return &CurveField{m: curveFieldArith.fromBig(value)}
+}
+
+// BigInt returns the canonical value of cf
+func (cf *CurveField) BigInt() *big.Int {
+	return curveFieldArith.toBig(&cf.m)
+}
+
+// Field returns cf as a coordinate field element, for use as a scalar of
+// Point.Mul
+func (cf *CurveField) Field() *Field {
+	return NewField(cf.BigInt())
 }
 
 func (cf *CurveField) Add(f *CurveField) *CurveField {
-	result := new(big.Int).Add(cf.v, f.v)
-	result.Mod(result, cf.modulus)
-	return &CurveField{v: result, modulus: cf.modulus}
+	return &CurveField{m: curveFieldArith.add(&cf.m, &f.m)}
 }
 
 func (cf *CurveField) Mul(f *CurveField) *CurveField {
-	result := new(big.Int).Mul(cf.v, f.v)
-	result.Mod(result, cf.modulus)
-	return &CurveField{v: result, modulus: cf.modulus}
+	return &CurveField{m: curveFieldArith.mul(&cf.m, &f.m)}
 }
 
 func (cf *CurveField) Sub(f *CurveField) *CurveField {
-	result := new(big.Int).Sub(cf.v, f.v)
-	result.Mod(result, cf.modulus)
-	return &CurveField{v: result, modulus: cf.modulus}
+	return &CurveField{m: curveFieldArith.sub(&cf.m, &f.m)}
 }
 
 func (cf *CurveField) Neg() *CurveField {
-	result := new(big.Int).Neg(cf.v)
-	result.Mod(result, cf.modulus)
-	return &CurveField{v: result, modulus: cf.modulus}
+	return &CurveField{m: curveFieldArith.neg(&cf.m)}
 }
 
+// Div returns cf / f, which is zero when f is zero
 func (cf *CurveField) Div(f *CurveField) *CurveField {
 	inv := f.Inv()
-	result := new(big.Int).Mul(cf.v, inv.v)
-	result.Mod(result, cf.modulus)
-	return &CurveField{v: result, modulus: cf.modulus}
+	return cf.Mul(inv)
 }
 
+// Inv returns 1 / cf, or zero for zero like Field.Inv
 func (cf *CurveField) Inv() *CurveField {
-	if cf.v.Cmp(big.NewInt(0)) == 0 {
-		panic("Cannot calculate the inverse of zero")
+	return &CurveField{m: curveFieldArith.inv(&cf.m)}
+}
+
+// InvChecked returns 1 / cf, and false if cf is zero
+func (cf *CurveField) InvChecked() (*CurveField, bool) {
+	if cf.IsZero() {
+		return nil, false
 	}
+	return cf.Inv(), true
+}
 
-	newt, t := big.NewInt(1), big.NewInt(0)
-	newr, r := new(big.Int).Set(cf.v), new(big.Int).Set(cf.modulus)
+func (cf *CurveField) String() string {
+	return cf.BigInt().String()
+}
 
-	for newr.Cmp(big.NewInt(0)) != 0 {
-		quotient := new(big.Int).Div(r, newr)
-		t, newt = newt, new(big.Int).Sub(t, new(big.Int).Mul(quotient, newt))
-		r, newr = newr, new(big.Int).Sub(r, new(big.Int).Mul(quotient, newr))
+// CurveFieldFromBytesLE reads a canonical 32-byte little-endian encoding
+func CurveFieldFromBytesLE(b []byte) (*CurveField, error) {
+	m, err := curveFieldArith.decode(b, true)
+	if err != nil {
+		return nil, err
 	}
+	return &CurveField{m: m}, nil
+}
 
-	if t.Cmp(big.NewInt(0)) < 0 {
-		t.Add(t, cf.modulus)
+// CurveFieldFromBytesBE reads a canonical 32-byte big-endian encoding
+func CurveFieldFromBytesBE(b []byte) (*CurveField, error) {
+	m, err := curveFieldArith.decode(b, false)
+	if err != nil {
+		return nil, err
 	}
+	return &CurveField{m: m}, nil
+}
 
-	return &CurveField{v: t, modulus: cf.modulus}
+// BytesLE returns cf as 32 little-endian bytes
+func (cf *CurveField) BytesLE() [32]byte {
+	return curveFieldArith.encode(&cf.m, true)
 }
 
-func (cf *CurveField) String() string {
-	return cf.v.String()
+// BytesBE returns cf as 32 big-endian bytes
+func (cf *CurveField) BytesBE() [32]byte {
+	return curveFieldArith.encode(&cf.m, false)
+}
+
+func (cf *CurveField) Equal(f *CurveField) bool {
+	return cf.m == f.m
+}
+
+func (cf *CurveField) IsZero() bool {
+	return cf.m.isZero()
+}
+
+func (cf *CurveField) Square() *CurveField {
+	return &CurveField{m: curveFieldArith.square(&cf.m)}
+}
+
+// Exp returns cf^e; a negative e raises the inverse of cf. It runs in
+// time depending on e, which must not be secret.
+func (cf *CurveField) Exp(e *big.Int) *CurveField {
+	return &CurveField{m: curveFieldArith.expBig(&cf.m, e)}
+}
+
+// Legendre returns 1 if cf is a non-zero square, -1 if it is not a square
+// and 0 if it is zero
+func (cf *CurveField) Legendre() int {
+	return curveFieldArith.legendre(&cf.m)
+}
+
+func (cf *CurveField) IsSquare() bool {
+	return cf.Legendre() >= 0
+}
+
+// Sqrt returns a square root of cf, the other one being its Neg, and false
+// if cf is not a square
+func (cf *CurveField) Sqrt() (*CurveField, bool) {
+	root, ok := curveFieldArith.sqrt(&cf.m)
+	if !ok {
+		return nil, false
+	}
+	return &CurveField{m: root}, true
 }
@@ -4,70 +4,127 @@ import (
 	"math/big"
 )
 
+// fieldArith is arithmetic modulo the BN254 scalar field order
+var fieldArith = newMontgomery("21888242871839275222246405745257275088548364400416034343698204186575808495617")
+
+// Field is an element of the BN254 scalar field, the coordinate field of
+// the curve
 type Field struct {
-	v       *big.Int
-	modulus *big.Int
+	// m is the value in Montgomery form
+	m limbs
 }
 
 func NewField(v *big.Int) *Field {
-	modulus := new(big.Int)
-	modulus.SetString("21888242871839275222246405745257275088548364400416034343698204186575808495617", 10)
-	value := new(big.Int).Mod(v, modulus)
-	return &Field{v: value, modulus: modulus}
+	return &Field{m: fieldArith.fromBig(v)}
+}
+
+// BigInt returns the canonical value of f
+func (f *Field) BigInt() *big.Int {
+	return fieldArith.toBig(&f.m)
 }
 
 func (f *Field) String() string {
-	return f.v.String()
+	return f.BigInt().String()
 }
 
 func (f *Field) Add(other *Field) *Field {
-	result := new(big.Int).Add(f.v, other.v)
-	result.Mod(result, f.modulus)
-	return &Field{v: result, modulus: f.modulus}
+	return &Field{m: fieldArith.add(&f.m, &other.m)}
 }
 
 func (f *Field) Mul(other *Field) *Field {
-	result := new(big.Int).Mul(f.v, other.v)
-	result.Mod(result, f.modulus)
-	return &Field{v: result, modulus: f.modulus}
+	return &Field{m: fieldArith.mul(&f.m, &other.m)}
 }
 
 func (f *Field) Sub(other *Field) *Field {
-	result := new(big.Int).Sub(f.v, other.v)
-	result.Mod(result, f.modulus)
-	return &Field{v: result, modulus: f.modulus}
+	return &Field{m: fieldArith.sub(&f.m, &other.m)}
 }
 
 func (f *Field) Neg() *Field {
-	result := new(big.Int).Neg(f.v)
-	result.Mod(result, f.modulus)
-	return &Field{v: result, modulus: f.modulus}
+	return &Field{m: fieldArith.neg(&f.m)}
 }
 
+// Div returns f / other, which is zero when other is zero
 func (f *Field) Div(other *Field) *Field {
 	inv := other.Inv()
-	result := new(big.Int).Mul(f.v, inv.v)
-	result.Mod(result, f.modulus)
-	return &Field{v: result, modulus: f.modulus}
+	return f.Mul(inv)
 }
 
+// Inv returns 1 / f. Zero has no inverse and returns zero rather than
+// panicking; use InvChecked where zero must be told apart.
 func (f *Field) Inv() *Field {
-	if f.v.Cmp(big.NewInt(0)) == 0 {
-		return f
-	}
+	return &Field{m: fieldArith.inv(&f.m)}
+}
 
-	newt, t := big.NewInt(1), big.NewInt(0)
-	newr, r := new(big.Int).Set(f.v), new(big.Int).Set(f.modulus)
+// InvChecked returns 1 / f, and false if f is zero
+func (f *Field) InvChecked() (*Field, bool) {
+	if f.IsZero() {
+		return nil, false
+	}
+	return f.Inv(), true
+}
 
-	for newr.Cmp(big.NewInt(0)) != 0 {
-		quotient := new(big.Int).Div(r, newr)
-		t, newt = newt, new(big.Int).Sub(t, new(big.Int).Mul(quotient, newt))
-		r, newr = newr, new(big.Int).Sub(r, new(big.Int).Mul(quotient, newr))
+// FieldFromBytesLE reads a canonical 32-byte little-endian encoding
+func FieldFromBytesLE(b []byte) (*Field, error) {
+	m, err := fieldArith.decode(b, true)
+	if err != nil {
+		return nil, err
 	}
+	return &Field{m: m}, nil
+}
 
-	if t.Cmp(big.NewInt(0)) < 0 {
-		t.Add(t, f.modulus)
+// FieldFromBytesBE reads a canonical 32-byte big-endian encoding
+func FieldFromBytesBE(b []byte) (*Field, error) {
+	m, err := fieldArith.decode(b, false)
+	if err != nil {
+		return nil, err
 	}
+	return &Field{m: m}, nil
+}
+
+// BytesLE returns f as 32 little-endian bytes
+func (f *Field) BytesLE() [32]byte {
+	return fieldArith.encode(&f.m, true)
+}
+
+// BytesBE returns f as 32 big-endian bytes
+func (f *Field) BytesBE() [32]byte {
+	return fieldArith.encode(&f.m, false)
+}
 
-	return &Field{v: t, modulus: f.modulus}
+func (f *Field) Equal(other *Field) bool {
+	return f.m == other.m
+}
+
+func (f *Field) IsZero() bool {
+	return f.m.isZero()
+}
+
+func (f *Field) Square() *Field {
+	return &Field{m: fieldArith.square(&f.m)}
+}
+
+// Exp returns f^e; a negative e raises the inverse of f. It runs in time
+// depending on e, which must not be secret.
+func (f *Field) Exp(e *big.Int) *Field {
+	return &Field{m: fieldArith.expBig(&f.m, e)}
+}
+
+// Legendre returns 1 if f is a non-zero square, -1 if it is not a square
+// and 0 if it is zero
+func (f *Field) Legendre() int {
+	return fieldArith.legendre(&f.m)
+}
+
+func (f *Field) IsSquare() bool {
+	return f.Legendre() >= 0
+}
+
+// Sqrt returns a square root of f, the other one being its Neg, and false
+// if f is not a square
+func (f *Field) Sqrt() (*Field, bool) {
+	root, ok := fieldArith.sqrt(&f.m)
+	if !ok {
+		return nil, false
+	}
+	return &Field{m: root}, true
 }
@@ -0,0 +1,206 @@
+package zkwasm
+
+import (
+	"math/big"
+	"math/rand"
+	"testing"
+)
+
+// testValues returns edge cases and random values below the modulus of m
+func testValues(m *montgomery, r *rand.Rand) []*big.Int {
+	values := []*big.Int{
+		big.NewInt(0),
+		big.NewInt(1),
+		big.NewInt(2),
+		new(big.Int).SetUint64(^uint64(0)),
+		new(big.Int).Lsh(big.NewInt(1), 128),
+		new(big.Int).Sub(m.modulus, big.NewInt(2)),
+		new(big.Int).Sub(m.modulus, big.NewInt(1)),
+	}
+	for i := 0; i < 32; i++ {
+		values = append(values, new(big.Int).Rand(r, m.modulus))
+	}
+	return values
+}
+
+func TestMontgomeryMatchesBig(t *testing.T) {
+	r := rand.New(rand.NewSource(1))
+	for _, m := range []*montgomery{fieldArith, curveFieldArith} {
+		mod := m.modulus
+		values := testValues(m, r)
+		for _, a := range values {
+			am := m.fromBig(a)
+			if got := m.toBig(&am); got.Cmp(a) != 0 {
+				t.Fatalf("round trip of %s = %s", a, got)
+			}
+			if got, want := m.toBig(ptr(m.neg(&am))), new(big.Int).Mod(new(big.Int).Neg(a), mod); got.Cmp(want) != 0 {
+				t.Fatalf("-%s = %s, want %s", a, got, want)
+			}
+			if a.Sign() != 0 {
+				if got, want := m.toBig(ptr(m.inv(&am))), new(big.Int).ModInverse(a, mod); got.Cmp(want) != 0 {
+					t.Fatalf("1/%s = %s, want %s", a, got, want)
+				}
+			}
+			if got, want := m.legendre(&am), big.Jacobi(a, mod); got != want {
+				t.Fatalf("legendre(%s) = %d, want %d", a, got, want)
+			}
+			if root, ok := m.sqrt(&am); ok != (big.Jacobi(a, mod) >= 0) {
+				t.Fatalf("sqrt(%s) ok = %v", a, ok)
+			} else if ok {
+				rb := m.toBig(&root)
+				if sq := new(big.Int).Mod(new(big.Int).Mul(rb, rb), mod); sq.Cmp(a) != 0 {
+					t.Fatalf("sqrt(%s)^2 = %s", a, sq)
+				}
+			}
+			for _, b := range values[:12] {
+				bm := m.fromBig(b)
+				if got, want := m.toBig(ptr(m.add(&am, &bm))), new(big.Int).Mod(new(big.Int).Add(a, b), mod); got.Cmp(want) != 0 {
+					t.Fatalf("%s + %s = %s, want %s", a, b, got, want)
+				}
+				if got, want := m.toBig(ptr(m.sub(&am, &bm))), new(big.Int).Mod(new(big.Int).Sub(a, b), mod); got.Cmp(want) != 0 {
+					t.Fatalf("%s - %s = %s, want %s", a, b, got, want)
+				}
+				if got, want := m.toBig(ptr(m.mul(&am, &bm))), new(big.Int).Mod(new(big.Int).Mul(a, b), mod); got.Cmp(want) != 0 {
+					t.Fatalf("%s * %s = %s, want %s", a, b, got, want)
+				}
+				if got, want := m.toBig(ptr(m.expBig(&am, b))), new(big.Int).Exp(a, b, mod); got.Cmp(want) != 0 {
+					t.Fatalf("%s ^ %s = %s, want %s", a, b, got, want)
+				}
+			}
+		}
+	}
+}
+
+func ptr(l limbs) *limbs {
+	return &l
+}
+
+func TestFieldMatchesBig(t *testing.T) {
+	r := rand.New(rand.NewSource(2))
+	mod := fieldArith.modulus
+	for i := 0; i < 64; i++ {
+		a, b := new(big.Int).Rand(r, mod), new(big.Int).Rand(r, mod)
+		fa, fb := NewField(a), NewField(b)
+		if got, want := fa.Mul(fb).BigInt(), new(big.Int).Mod(new(big.Int).Mul(a, b), mod); got.Cmp(want) != 0 {
+			t.Fatalf("%s * %s = %s, want %s", a, b, got, want)
+		}
+		if got, want := fa.Square().BigInt(), new(big.Int).Mod(new(big.Int).Mul(a, a), mod); got.Cmp(want) != 0 {
+			t.Fatalf("%s^2 = %s, want %s", a, got, want)
+		}
+		if b.Sign() != 0 {
+			want := new(big.Int).Mul(a, new(big.Int).ModInverse(b, mod))
+			if got := fa.Div(fb).BigInt(); got.Cmp(want.Mod(want, mod)) != 0 {
+				t.Fatalf("%s / %s = %s, want %s", a, b, got, want)
+			}
+		}
+		le := fa.BytesLE()
+		if back, err := FieldFromBytesLE(le[:]); err != nil || !back.Equal(fa) {
+			t.Fatalf("LE round trip of %s = %v, %v", a, back, err)
+		}
+		be := fa.BytesBE()
+		if got := new(big.Int).SetBytes(be[:]); got.Cmp(a) != 0 {
+			t.Fatalf("BE bytes of %s = %s", a, got)
+		}
+	}
+	// values at or above the modulus reduce on construction but are not
+	// canonical encodings
+	if got := NewField(new(big.Int).Add(mod, big.NewInt(5))).BigInt(); got.Int64() != 5 {
+		t.Fatalf("p + 5 = %s, want 5", got)
+	}
+	var buf [32]byte
+	mod.FillBytes(buf[:])
+	if _, err := FieldFromBytesBE(buf[:]); err == nil {
+		t.Fatal("the modulus decoded as a canonical encoding")
+	}
+}
+
+func TestFieldSqrtMatchesModSqrt(t *testing.T) {
+	r := rand.New(rand.NewSource(5))
+	mod := fieldArith.modulus
+	values := testValues(fieldArith, r)
+	// 5 is the smallest non-residue mod p, and so is 5 times a square
+	five := big.NewInt(5)
+	values = append(values, five, new(big.Int).Mod(new(big.Int).Mul(five, big.NewInt(49)), mod))
+	var residues, nonResidues int
+	for _, a := range values {
+		fa := NewField(a)
+		if got, want := fa.Legendre(), big.Jacobi(a, mod); got != want {
+			t.Fatalf("Legendre(%s) = %d, want %d", a, got, want)
+		}
+		want := new(big.Int).ModSqrt(a, mod)
+		root, ok := fa.Sqrt()
+		if ok != (want != nil) || fa.IsSquare() != ok {
+			t.Fatalf("Sqrt(%s) ok = %v, IsSquare = %v, ModSqrt = %v", a, ok, fa.IsSquare(), want)
+		}
+		if !ok {
+			nonResidues++
+			continue
+		}
+		residues++
+		got := root.BigInt()
+		if got.Cmp(want) != 0 && got.Cmp(new(big.Int).Mod(new(big.Int).Neg(want), mod)) != 0 {
+			t.Fatalf("Sqrt(%s) = %s, want ±%s", a, got, want)
+		}
+		if !root.Square().Equal(fa) {
+			t.Fatalf("Sqrt(%s)^2 = %s", a, root.Square())
+		}
+	}
+	if residues < 4 || nonResidues < 4 {
+		t.Fatalf("%d residues and %d non-residues tested, want both covered", residues, nonResidues)
+	}
+}
+
+func TestFieldBytesAtModulusEdges(t *testing.T) {
+	mod := fieldArith.modulus
+	for _, a := range []*big.Int{big.NewInt(0), big.NewInt(1), new(big.Int).Sub(mod, big.NewInt(1))} {
+		fa := NewField(a)
+		le, be := fa.BytesLE(), fa.BytesBE()
+		if back, err := FieldFromBytesLE(le[:]); err != nil || back.BigInt().Cmp(a) != 0 {
+			t.Fatalf("LE round trip of %s = %v, %v", a, back, err)
+		}
+		if back, err := FieldFromBytesBE(be[:]); err != nil || back.BigInt().Cmp(a) != 0 {
+			t.Fatalf("BE round trip of %s = %v, %v", a, back, err)
+		}
+		if got := new(big.Int).SetBytes(be[:]); got.Cmp(a) != 0 {
+			t.Fatalf("BE bytes of %s = %s", a, got)
+		}
+	}
+	max := new(big.Int).Sub(new(big.Int).Lsh(big.NewInt(1), 256), big.NewInt(1))
+	for _, a := range []*big.Int{mod, new(big.Int).Add(mod, big.NewInt(1)), max} {
+		var be, le [32]byte
+		a.FillBytes(be[:])
+		for i := range be {
+			le[i] = be[31-i]
+		}
+		if _, err := FieldFromBytesBE(be[:]); err == nil {
+			t.Fatalf("%s decoded from big-endian bytes", a)
+		}
+		if _, err := FieldFromBytesLE(le[:]); err == nil {
+			t.Fatalf("%s decoded from little-endian bytes", a)
+		}
+	}
+	if _, err := FieldFromBytesLE(make([]byte, 31)); err == nil {
+		t.Fatal("31 bytes decoded")
+	}
+}
+
+func BenchmarkFieldMul(b *testing.B) {
+	r := rand.New(rand.NewSource(3))
+	x := NewField(new(big.Int).Rand(r, fieldArith.modulus))
+	y := NewField(new(big.Int).Rand(r, fieldArith.modulus))
+	b.ReportAllocs()
+	b.ResetTimer()
+	for i := 0; i < b.N; i++ {
+		x = x.Mul(y)
+	}
+}
+
+func BenchmarkFieldInv(b *testing.B) {
+	r := rand.New(rand.NewSource(4))
+	x := NewField(new(big.Int).Rand(r, fieldArith.modulus))
+	b.ReportAllocs()
+	b.ResetTimer()
+	for i := 0; i < b.N; i++ {
+		x.Inv()
+	}
+}
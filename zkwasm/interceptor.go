@@ -0,0 +1,117 @@
+package zkwasm
+
+import (
+	"bytes"
+	"context"
+	"encoding/json"
+	"io"
+	"net/http"
+)
+
+// Call is one request to the rollup server as seen by interceptors
+type Call struct {
+	// Endpoint is one of EndpointSend, EndpointQuery, EndpointConfig or
+	// EndpointJob
+	Endpoint string
+	Method   string
+	// Path is the request path, such as /job/42
+	Path string
+	// Payload is the JSON body, nil for requests without one
+	Payload map[string]string
+	// Header holds extra request headers
+	Header http.Header
+}
+
+// Handler performs a call and returns the decoded response
+type Handler func(ctx context.Context, call *Call) (map[string]interface{}, error)
+
+// Interceptor wraps a call. It may inspect or change the call before
+// invoking next, inspect or replace the response after it, or answer
+// without invoking next at all.
+type Interceptor func(ctx context.Context, call *Call, next Handler) (map[string]interface{}, error)
+
+// WithInterceptors adds interceptors around every request. The first one
+// added is the outermost.
+func WithInterceptors(interceptors ...Interceptor) Option {
+	return func(rpc *ZKWasmAppRpc) {
+		rpc.interceptors = append(rpc.interceptors, interceptors...)
+	}
+}
+
+// Hooks builds an Interceptor from a before and an after hook, either of
+// which may be nil. An error from before aborts the call.
+func Hooks(before func(ctx context.Context, call *Call) error, after func(ctx context.Context, call *Call, resp map[string]interface{}, err error)) Interceptor {
+	return func(ctx context.Context, call *Call, next Handler) (map[string]interface{}, error) {
+		if before != nil {
+			if err := before(ctx, call); err != nil {
+				return nil, err
+			}
+		}
+		resp, err := next(ctx, call)
+		if after != nil {
+			after(ctx, call, resp, err)
+		}
+		return resp, err
+	}
+}
+
+// callErrorOps keeps the error op names each endpoint reported before the
+// requests were unified
+var callErrorOps = map[string]string{
+	EndpointSend:   "SendTransactionError",
+	EndpointQuery:  "UnexpectedResponseStatus",
+	EndpointConfig: "QueryConfigError",
+	EndpointJob:    "QueryJobError",
+}
+
+// chain composes the interceptors around execute, the first one outermost
+func (rpc *ZKWasmAppRpc) chain() Handler {
+	handler := Handler(rpc.execute)
+	for i := len(rpc.interceptors) - 1; i >= 0; i-- {
+		interceptor, next := rpc.interceptors[i], handler
+		handler = func(ctx context.Context, call *Call) (map[string]interface{}, error) {
+			return interceptor(ctx, call, next)
+		}
+	}
+	return handler
+}
+
+// do runs call through the interceptors and the HTTP request path
+func (rpc *ZKWasmAppRpc) do(ctx context.Context, call *Call) (map[string]interface{}, error) {
+	return rpc.handler(ctx, call)
+}
+
+// execute sends call to the server and decodes its 201 response
+func (rpc *ZKWasmAppRpc) execute(ctx context.Context, call *Call) (map[string]interface{}, error) {
+	var body io.Reader
+	if call.Payload != nil {
+		jsonData, err := json.Marshal(call.Payload)
+		if err != nil {
+			return nil, err
+		}
+		body = bytes.NewBuffer(jsonData)
+	}
+	req, err := http.NewRequestWithContext(ctx, call.Method, rpc.baseURL+call.Path, body)
+	if err != nil {
+		return nil, err
+	}
+	req.Header.Set("Content-Type", "application/json")
+	for name, values := range call.Header {
+		req.Header[name] = values
+	}
+	resp, err := rpc.roundTrip(req, call.Endpoint, call.Payload["pkx"])
+	if err != nil {
+		return nil, err
+	}
+	defer resp.Body.Close()
+
+	if resp.StatusCode == http.StatusCreated {
+		var result map[string]interface{}
+		err = json.NewDecoder(resp.Body).Decode(&result)
+		if err != nil {
+			return nil, err
+		}
+		return result, nil
+	}
+	return nil, newStatusError(callErrorOps[call.Endpoint], resp)
+}
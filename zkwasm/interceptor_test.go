@@ -0,0 +1,151 @@
+package zkwasm
+
+import (
+	"context"
+	"errors"
+	"net/http"
+	"net/http/httptest"
+	"reflect"
+	"sync/atomic"
+	"testing"
+)
+
+// tracing returns an interceptor appending name before and after next to
+// events
+func tracing(name string, events *[]string) Interceptor {
+	return func(ctx context.Context, call *Call, next Handler) (map[string]interface{}, error) {
+		*events = append(*events, name+" before "+call.Endpoint)
+		resp, err := next(ctx, call)
+		*events = append(*events, name+" after")
+		return resp, err
+	}
+}
+
+func TestInterceptorOrder(t *testing.T) {
+	var header atomic.Value
+	server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		header.Store(r.Header.Get("Authorization"))
+		reply(w, map[string]interface{}{"success": true, "data": "{}"})
+	}))
+	defer server.Close()
+
+	var events []string
+	auth := func(ctx context.Context, call *Call, next Handler) (map[string]interface{}, error) {
+		if call.Header == nil {
+			call.Header = http.Header{}
+		}
+		call.Header.Set("Authorization", "Bearer token")
+		return next(ctx, call)
+	}
+	rpc := NewZKWasmAppRpc(server.URL, WithInterceptors(tracing("a", &events), tracing("b", &events)), WithInterceptors(auth))
+	if _, err := rpc.QueryConfig(); err != nil {
+		t.Fatal(err)
+	}
+	want := []string{"a before /config", "b before /config", "b after", "a after"}
+	if !reflect.DeepEqual(events, want) {
+		t.Fatalf("events = %v, want %v", events, want)
+	}
+	if got := header.Load(); got != "Bearer token" {
+		t.Fatalf("Authorization = %v, want the header set by the interceptor", got)
+	}
+}
+
+func TestInterceptorShortCircuit(t *testing.T) {
+	var hits atomic.Int32
+	server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		hits.Add(1)
+	}))
+	defer server.Close()
+
+	var events []string
+	cached := map[string]interface{}{"success": true, "data": "{}"}
+	cache := func(ctx context.Context, call *Call, next Handler) (map[string]interface{}, error) {
+		if call.Endpoint == EndpointConfig {
+			return cached, nil
+		}
+		return next(ctx, call)
+	}
+	rpc := NewZKWasmAppRpc(server.URL, WithInterceptors(tracing("outer", &events), cache, tracing("inner", &events)))
+	resp, err := rpc.QueryConfig()
+	if err != nil {
+		t.Fatal(err)
+	}
+	if !reflect.DeepEqual(resp, cached) || hits.Load() != 0 {
+		t.Fatalf("resp = %v after %d requests, want the cached response without a request", resp, hits.Load())
+	}
+	if want := []string{"outer before /config", "outer after"}; !reflect.DeepEqual(events, want) {
+		t.Fatalf("events = %v, want %v", events, want)
+	}
+}
+
+func TestInterceptorErrors(t *testing.T) {
+	server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		http.Error(w, "unavailable", http.StatusServiceUnavailable)
+	}))
+	defer server.Close()
+
+	// server errors reach every interceptor on the way out
+	var seen error
+	observe := func(ctx context.Context, call *Call, next Handler) (map[string]interface{}, error) {
+		resp, err := next(ctx, call)
+		seen = err
+		return resp, err
+	}
+	rpc := NewZKWasmAppRpc(server.URL, WithInterceptors(observe))
+	_, err := rpc.QueryConfig()
+	var statusErr *StatusError
+	if !errors.As(err, &statusErr) || statusErr.StatusCode != http.StatusServiceUnavailable || seen != err {
+		t.Fatalf("err = %v, interceptor saw %v; want the 503 in both", err, seen)
+	}
+
+	// an interceptor error is returned as is
+	injected := errors.New("injected fault")
+	fault := func(ctx context.Context, call *Call, next Handler) (map[string]interface{}, error) {
+		return nil, injected
+	}
+	rpc = NewZKWasmAppRpc(server.URL, WithInterceptors(fault))
+	if _, err := rpc.QueryStatePkx(context.Background(), "1"); err != injected {
+		t.Fatalf("err = %v, want the injected fault", err)
+	}
+}
+
+func TestHooks(t *testing.T) {
+	_, server := newFakeRollup(t)
+	var before, after []string
+	var afterErr error
+	hooks := Hooks(func(ctx context.Context, call *Call) error {
+		before = append(before, call.Endpoint+" "+call.Payload["pkx"])
+		return nil
+	}, func(ctx context.Context, call *Call, resp map[string]interface{}, err error) {
+		after = append(after, call.Endpoint)
+		afterErr = err
+		if resp["success"] != true {
+			t.Errorf("after saw response %v", resp)
+		}
+	})
+	rpc := NewZKWasmAppRpc(server.URL, WithInterceptors(hooks))
+	if _, err := rpc.QueryStatePkx(context.Background(), "1"); err != nil {
+		t.Fatal(err)
+	}
+	if !reflect.DeepEqual(before, []string{"/query 1"}) || !reflect.DeepEqual(after, []string{"/query"}) || afterErr != nil {
+		t.Fatalf("before = %v, after = %v, err = %v", before, after, afterErr)
+	}
+
+	// a before error aborts the call and skips after
+	refused := errors.New("refused")
+	afterCalled := false
+	rpc = NewZKWasmAppRpc(server.URL, WithInterceptors(Hooks(func(context.Context, *Call) error {
+		return refused
+	}, func(context.Context, *Call, map[string]interface{}, error) {
+		afterCalled = true
+	})))
+	if _, err := rpc.QueryStatePkx(context.Background(), "1"); err != refused || afterCalled {
+		t.Fatalf("err = %v, after called = %v; want the before error and no after", err, afterCalled)
+	}
+
+	// nil hooks pass the call through
+	rpc = NewZKWasmAppRpc(server.URL, WithInterceptors(Hooks(nil, nil)))
+	if _, err := rpc.QueryStatePkx(context.Background(), "1"); err != nil {
+		t.Fatal(err)
+	}
+}
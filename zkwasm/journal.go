@@ -0,0 +1,347 @@
+package zkwasm
+
+import (
+	"bufio"
+	"context"
+	"encoding/json"
+	"errors"
+	"math/big"
+	"os"
+	"path/filepath"
+	"sync"
+	"time"
+)
+
+// TxStatus is the lifecycle state of a journaled transaction
+type TxStatus string
+
+const (
+	// TxSigned means the payload was signed but /send has not accepted it
+	TxSigned TxStatus = "signed"
+	// TxSubmitted means /send returned a job id the job has not finished
+	TxSubmitted TxStatus = "submitted"
+	// TxFinished means the job finished or the nonce shows it landed
+	TxFinished TxStatus = "finished"
+	// TxFailed means the application rejected the job
+	TxFailed TxStatus = "failed"
+)
+
+// TxRecord is the journaled state of one transaction. It holds the signed
+// payload, never key material.
+type TxRecord struct {
+	ID      string            `json:"id"`
+	Pkx     string            `json:"pkx"`
+	Nonce   uint64            `json:"nonce"`
+	Payload map[string]string `json:"payload"`
+	JobID   string            `json:"jobid,omitempty"`
+	Status  TxStatus          `json:"status"`
+	Result  string            `json:"result,omitempty"`
+	Error   string            `json:"error,omitempty"`
+	Updated time.Time         `json:"updated"`
+}
+
+// Unfinished reports whether the outcome of the transaction is unknown
+func (r *TxRecord) Unfinished() bool {
+	return r.Status == TxSigned || r.Status == TxSubmitted
+}
+
+// TxJournal persists transaction records. Save replaces the record with
+// the same ID.
+type TxJournal interface {
+	Save(record TxRecord) error
+	Unfinished() ([]TxRecord, error)
+}
+
+// signed points record at a newly signed payload of the transaction. The
+// first signature names the record; a re-signed retry keeps the name so
+// the journal holds one record per transaction whatever its attempts.
+func (r *TxRecord) signed(pkx string, cmd [4]*big.Int, payload map[string]string) error {
+	command, err := DecodeCommand(cmd[0])
+	if err != nil {
+		return err
+	}
+	if r.ID == "" {
+		r.ID = pkx + ":" + payload["msg"]
+	}
+	r.Pkx = pkx
+	r.Nonce = command.Nonce
+	r.Payload = payload
+	r.JobID = ""
+	r.Status = TxSigned
+	r.Result = ""
+	r.Error = ""
+	return nil
+}
+
+// journalError records err on record, returning err joined with any
+// failure to save it
+func (rpc *ZKWasmAppRpc) journalError(record *TxRecord, err error) error {
+	if record == nil {
+		return err
+	}
+	record.Error = err.Error()
+	if saveErr := rpc.journal.Save(*record); saveErr != nil {
+		return errors.Join(err, saveErr)
+	}
+	return err
+}
+
+// journalOutcome records the outcome of the job of record, returning err
+// joined with any failure to save it
+func (rpc *ZKWasmAppRpc) journalOutcome(record *TxRecord, result *TransactionResult, err error) error {
+	if record == nil {
+		return err
+	}
+	var failed *JobFailedError
+	switch {
+	case err == nil:
+		record.Status = TxFinished
+		record.Result = result.ReturnValue
+		record.Error = ""
+	case errors.As(err, &failed):
+		record.Status = TxFailed
+		record.Error = failed.Reason
+	default:
+		// timeouts leave the record submitted for Recover
+		record.Error = err.Error()
+	}
+	if saveErr := rpc.journal.Save(*record); saveErr != nil {
+		return errors.Join(err, saveErr)
+	}
+	return err
+}
+
+// RecoveryOutcome describes what Recover did with an unfinished record
+type RecoveryOutcome struct {
+	Record TxRecord
+	// Resubmitted reports the signed payload was sent again
+	Resubmitted bool
+	Err         error
+}
+
+// Recover resolves the unfinished transactions of the journal, typically
+// on start-up. A record with a job id is re-polled first. When the job
+// cannot settle it, the player nonce from /query decides: a nonce past the
+// record's means the transaction landed, otherwise the original signed
+// payload is submitted again, which cannot double-apply since the rollup
+// accepts each nonce once.
+//
+// The nonce check assumes nothing but this journal's client sends with the
+// key. A transaction sent for the same key elsewhere also moves the nonce,
+// and Recover would then mark a record finished that never landed.
+func (rpc *ZKWasmAppRpc) Recover(ctx context.Context) ([]RecoveryOutcome, error) {
+	if rpc.journal == nil {
+		return nil, nil
+	}
+	records, err := rpc.journal.Unfinished()
+	if err != nil {
+		return nil, err
+	}
+	var outcomes []RecoveryOutcome
+	for _, record := range records {
+		outcome := rpc.recoverRecord(ctx, record)
+		outcomes = append(outcomes, outcome)
+		if ctx.Err() != nil {
+			return outcomes, ctx.Err()
+		}
+	}
+	return outcomes, nil
+}
+
+func (rpc *ZKWasmAppRpc) recoverRecord(ctx context.Context, record TxRecord) RecoveryOutcome {
+	if record.JobID != "" {
+		if jobStatus, err := rpc.queryJobStatus(ctx, record.JobID); err == nil {
+			result, err := jobResult(record.JobID, jobStatus)
+			if result != nil || err != nil {
+				err = rpc.journalOutcome(&record, result, err)
+				return RecoveryOutcome{Record: record, Err: err}
+			}
+		}
+	}
+
+	nonce, err := rpc.GetNoncePkx(ctx, record.Pkx)
+	if err != nil {
+		return RecoveryOutcome{Record: record, Err: err}
+	}
+	if nonce.Uint64() > record.Nonce {
+		record.Status = TxFinished
+		record.Error = ""
+		return RecoveryOutcome{Record: record, Err: rpc.journal.Save(record)}
+	}
+
+	jobID, err := rpc.submit(ctx, record.Payload)
+	if err != nil {
+		err = rpc.journalError(&record, err)
+		return RecoveryOutcome{Record: record, Resubmitted: true, Err: err}
+	}
+	record.Status = TxSubmitted
+	record.JobID = jobID
+	if err := rpc.journal.Save(record); err != nil {
+		return RecoveryOutcome{Record: record, Resubmitted: true, Err: err}
+	}
+	result, err := rpc.waitJob(ctx, jobID)
+	err = rpc.journalOutcome(&record, result, err)
+	return RecoveryOutcome{Record: record, Resubmitted: true, Err: err}
+}
+
+// FileTxJournal is a TxJournal stored as an append-only JSON lines file.
+// Every Save appends a line, so call Compact from time to time to bound
+// its size.
+type FileTxJournal struct {
+	mu      sync.Mutex
+	path    string
+	file    *os.File
+	records map[string]TxRecord
+	order   []string
+}
+
+// OpenFileTxJournal opens or creates the journal at path and replays it
+func OpenFileTxJournal(path string) (*FileTxJournal, error) {
+	file, err := os.OpenFile(path, os.O_RDWR|os.O_CREATE|os.O_APPEND, 0600)
+	if err != nil {
+		return nil, err
+	}
+	j := &FileTxJournal{path: path, file: file, records: make(map[string]TxRecord)}
+	scanner := bufio.NewScanner(file)
+	scanner.Buffer(make([]byte, 64*1024), 1024*1024)
+	for scanner.Scan() {
+		var record TxRecord
+		if err := json.Unmarshal(scanner.Bytes(), &record); err != nil {
+			// a torn final line from a crash is ignored
+			continue
+		}
+		j.remember(record)
+	}
+	if err := scanner.Err(); err != nil {
+		file.Close()
+		return nil, err
+	}
+	if info, err := file.Stat(); err == nil && info.Size() > 0 {
+		last := make([]byte, 1)
+		if _, err := file.ReadAt(last, info.Size()-1); err == nil && last[0] != '\n' {
+			if _, err := file.Write([]byte{'\n'}); err != nil {
+				file.Close()
+				return nil, err
+			}
+		}
+	}
+	return j, nil
+}
+
+func (j *FileTxJournal) remember(record TxRecord) {
+	if _, ok := j.records[record.ID]; !ok {
+		j.order = append(j.order, record.ID)
+	}
+	j.records[record.ID] = record
+}
+
+// Save durably appends record
+func (j *FileTxJournal) Save(record TxRecord) error {
+	record.Updated = time.Now()
+	line, err := json.Marshal(record)
+	if err != nil {
+		return err
+	}
+	j.mu.Lock()
+	defer j.mu.Unlock()
+	if _, err := j.file.Write(append(line, '\n')); err != nil {
+		return err
+	}
+	if err := j.file.Sync(); err != nil {
+		return err
+	}
+	j.remember(record)
+	return nil
+}
+
+// Compact rewrites the journal with one line per record, its latest state,
+// dropping the finished and failed records keep rejects. Unfinished records
+// are always kept; a nil keep keeps every record. The new file replaces the
+// old one atomically, so a crash during Compact leaves either intact.
+func (j *FileTxJournal) Compact(keep func(TxRecord) bool) error {
+	j.mu.Lock()
+	defer j.mu.Unlock()
+
+	tmp, err := os.CreateTemp(filepath.Dir(j.path), filepath.Base(j.path)+".compact-*")
+	if err != nil {
+		return err
+	}
+	var order []string
+	err = func() error {
+		writer := bufio.NewWriter(tmp)
+		for _, id := range j.order {
+			record := j.records[id]
+			if !record.Unfinished() && keep != nil && !keep(record) {
+				continue
+			}
+			line, err := json.Marshal(record)
+			if err != nil {
+				return err
+			}
+			writer.Write(append(line, '\n'))
+			order = append(order, id)
+		}
+		if err := writer.Flush(); err != nil {
+			return err
+		}
+		if err := tmp.Sync(); err != nil {
+			return err
+		}
+		return os.Rename(tmp.Name(), j.path)
+	}()
+	if err != nil {
+		tmp.Close()
+		os.Remove(tmp.Name())
+		return err
+	}
+	if dir, err := os.Open(filepath.Dir(j.path)); err == nil {
+		dir.Sync()
+		dir.Close()
+	}
+
+	// the temporary file is the journal now and its offset is at the end,
+	// so later saves append to it
+	j.file.Close()
+	j.file = tmp
+	records := make(map[string]TxRecord, len(order))
+	for _, id := range order {
+		records[id] = j.records[id]
+	}
+	j.records = records
+	j.order = order
+	return nil
+}
+
+// UpdatedSince returns a Compact filter keeping the records updated at or
+// after t
+func UpdatedSince(t time.Time) func(TxRecord) bool {
+	return func(record TxRecord) bool {
+		return !record.Updated.Before(t)
+	}
+}
+
+// Unfinished returns the records whose outcome is unknown, oldest first
+func (j *FileTxJournal) Unfinished() ([]TxRecord, error) {
+	j.mu.Lock()
+	defer j.mu.Unlock()
+	var records []TxRecord
+	for _, id := range j.order {
+		if record := j.records[id]; record.Unfinished() {
+			records = append(records, record)
+		}
+	}
+	return records, nil
+}
+
+// Get returns the latest record with the given id
+func (j *FileTxJournal) Get(id string) (TxRecord, bool) {
+	j.mu.Lock()
+	defer j.mu.Unlock()
+	record, ok := j.records[id]
+	return record, ok
+}
+
+// Close closes the journal file
+func (j *FileTxJournal) Close() error {
+	return j.file.Close()
+}
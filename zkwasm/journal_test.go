@@ -0,0 +1,280 @@
+package zkwasm
+
+import (
+	"context"
+	"errors"
+	"math/big"
+	"os"
+	"path/filepath"
+	"strings"
+	"testing"
+	"time"
+)
+
+func TestFileTxJournalReplay(t *testing.T) {
+	path := filepath.Join(t.TempDir(), "tx.jsonl")
+	j, err := OpenFileTxJournal(path)
+	if err != nil {
+		t.Fatal(err)
+	}
+	j.Save(TxRecord{ID: "a", Status: TxSigned, Nonce: 1})
+	j.Save(TxRecord{ID: "b", Status: TxSubmitted, Nonce: 2, JobID: "7"})
+	j.Save(TxRecord{ID: "a", Status: TxFinished, Nonce: 1})
+	j.Close()
+
+	// a crash in the middle of a write leaves a torn last line
+	file, err := os.OpenFile(path, os.O_APPEND|os.O_WRONLY, 0600)
+	if err != nil {
+		t.Fatal(err)
+	}
+	file.WriteString(`{"id":"c","status":"sig`)
+	file.Close()
+
+	j, err = OpenFileTxJournal(path)
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer j.Close()
+	unfinished, _ := j.Unfinished()
+	if len(unfinished) != 1 || unfinished[0].ID != "b" || unfinished[0].JobID != "7" {
+		t.Fatalf("unfinished = %+v, want only b", unfinished)
+	}
+	if err := j.Save(TxRecord{ID: "d", Status: TxSigned}); err != nil {
+		t.Fatal(err)
+	}
+	j.Close()
+	j, err = OpenFileTxJournal(path)
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer j.Close()
+	if _, ok := j.Get("d"); !ok {
+		t.Fatal("record written after a torn line was lost")
+	}
+}
+
+// crashedRecord journals cmd as signed by key without sending it, as if
+// the process died before /send
+func crashedRecord(t *testing.T, journal TxJournal, prikey string, cmd [4]*big.Int) TxRecord {
+	t.Helper()
+	signer := NewKeySigner(prikey)
+	payload, _ := signer.Sign(cmd)
+	var record TxRecord
+	if err := record.signed(signer.Pkx(), cmd, payload); err != nil {
+		t.Fatal(err)
+	}
+	if err := journal.Save(record); err != nil {
+		t.Fatal(err)
+	}
+	return record
+}
+
+func TestRecoverResubmitsUnsentPayload(t *testing.T) {
+	fake, server := newFakeRollup(t)
+	journal, err := OpenFileTxJournal(filepath.Join(t.TempDir(), "tx.jsonl"))
+	if err != nil {
+		t.Fatal(err)
+	}
+	record := crashedRecord(t, journal, "1234", testCommand(0))
+
+	rpc := NewZKWasmAppRpc(server.URL, WithJournal(journal))
+	outcomes, err := rpc.Recover(context.Background())
+	if err != nil {
+		t.Fatal(err)
+	}
+	if len(outcomes) != 1 || !outcomes[0].Resubmitted || outcomes[0].Err != nil {
+		t.Fatalf("outcomes = %+v", outcomes)
+	}
+	if got, _ := journal.Get(record.ID); got.Status != TxFinished || got.JobID == "" {
+		t.Fatalf("record = %+v, want finished with a job", got)
+	}
+	if fake.appliedCount() != 1 {
+		t.Fatalf("applied %d times", fake.appliedCount())
+	}
+
+	// a second recovery has nothing left to do
+	if outcomes, _ := rpc.Recover(context.Background()); len(outcomes) != 0 {
+		t.Fatalf("second recovery: %+v", outcomes)
+	}
+}
+
+func TestRecoverLandedByNonce(t *testing.T) {
+	fake, server := newFakeRollup(t)
+	journal, err := OpenFileTxJournal(filepath.Join(t.TempDir(), "tx.jsonl"))
+	if err != nil {
+		t.Fatal(err)
+	}
+	record := crashedRecord(t, journal, "1234", testCommand(0))
+	// the send landed before the crash but its job id was never recorded
+	fake.nonces[record.Pkx] = 1
+
+	rpc := NewZKWasmAppRpc(server.URL, WithJournal(journal))
+	outcomes, err := rpc.Recover(context.Background())
+	if err != nil {
+		t.Fatal(err)
+	}
+	if len(outcomes) != 1 || outcomes[0].Resubmitted || outcomes[0].Record.Status != TxFinished {
+		t.Fatalf("outcomes = %+v", outcomes)
+	}
+	if fake.sends != 0 {
+		t.Fatalf("resent %d times", fake.sends)
+	}
+}
+
+func TestRecoverPollsRecordedJob(t *testing.T) {
+	fake, server := newFakeRollup(t)
+	journal, err := OpenFileTxJournal(filepath.Join(t.TempDir(), "tx.jsonl"))
+	if err != nil {
+		t.Fatal(err)
+	}
+	record := crashedRecord(t, journal, "1234", testCommand(0))
+	record.Status = TxSubmitted
+	record.JobID = "1"
+	journal.Save(record)
+	fake.jobs["1"] = 1
+
+	rpc := NewZKWasmAppRpc(server.URL, WithJournal(journal))
+	outcomes, err := rpc.Recover(context.Background())
+	if err != nil {
+		t.Fatal(err)
+	}
+	if len(outcomes) != 1 || outcomes[0].Record.Status != TxFinished || outcomes[0].Record.Result != `{"limb":1}` {
+		t.Fatalf("outcomes = %+v", outcomes)
+	}
+}
+
+// failingJournal accepts records until fail is set
+type failingJournal struct {
+	records []TxRecord
+	fail    bool
+}
+
+var errDiskFull = errors.New("disk full")
+
+func (j *failingJournal) Save(record TxRecord) error {
+	if j.fail {
+		return errDiskFull
+	}
+	j.records = append(j.records, record)
+	return nil
+}
+
+func (j *failingJournal) Unfinished() ([]TxRecord, error) {
+	return j.records, nil
+}
+
+func TestRecoverReportsJournalErrors(t *testing.T) {
+	fake, server := newFakeRollup(t)
+	journal := &failingJournal{}
+	record := crashedRecord(t, journal, "1234", testCommand(0))
+	fake.nonces[record.Pkx] = 1
+	journal.fail = true
+
+	rpc := NewZKWasmAppRpc(server.URL, WithJournal(journal))
+	outcomes, err := rpc.Recover(context.Background())
+	if err != nil {
+		t.Fatal(err)
+	}
+	if len(outcomes) != 1 || !errors.Is(outcomes[0].Err, errDiskFull) {
+		t.Fatalf("outcomes = %+v, want the journal error", outcomes)
+	}
+}
+
+func TestSendReportsJournalErrors(t *testing.T) {
+	_, server := newFakeRollup(t)
+	journal := &failingJournal{}
+	rpc := NewZKWasmAppRpc(server.URL, WithJournal(journal))
+	journal.fail = true
+	_, err := rpc.SendTransactionWithSigner(context.Background(), NewKeySigner("1234"), testCommand(0))
+	if !errors.Is(err, errDiskFull) {
+		t.Fatalf("err = %v, want the journal error", err)
+	}
+}
+
+func TestResignedRetryUpdatesRecord(t *testing.T) {
+	fake, server := newFakeRollup(t)
+	journal, err := OpenFileTxJournal(filepath.Join(t.TempDir(), "tx.jsonl"))
+	if err != nil {
+		t.Fatal(err)
+	}
+	signer := NewKeySigner("1234")
+	// the command was built with a stale nonce
+	fake.nonces[signer.Pkx()] = 1
+	policy := RetryPolicy{MaxAttempts: 3, InitialBackoff: time.Millisecond}
+	rpc := NewZKWasmAppRpc(server.URL, WithJournal(journal), WithRetry(policy))
+	if _, err := rpc.SendTransactionWithSigner(context.Background(), signer, testCommand(0)); err != nil {
+		t.Fatal(err)
+	}
+	unfinished, _ := journal.Unfinished()
+	if len(unfinished) != 0 {
+		t.Fatalf("superseded attempts left unfinished: %+v", unfinished)
+	}
+	if len(journal.order) != 1 {
+		t.Fatalf("journal holds %d records, want one per transaction", len(journal.order))
+	}
+	if record := journal.records[journal.order[0]]; record.Status != TxFinished || record.Nonce != 1 {
+		t.Fatalf("record = %+v, want finished at nonce 1", record)
+	}
+}
+
+func TestFileTxJournalCompact(t *testing.T) {
+	path := filepath.Join(t.TempDir(), "tx.jsonl")
+	j, err := OpenFileTxJournal(path)
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer j.Close()
+	for i := 0; i < 10; i++ {
+		j.Save(TxRecord{ID: "old", Status: TxSubmitted, Nonce: 1})
+	}
+	j.Save(TxRecord{ID: "old", Status: TxFinished, Nonce: 1})
+	j.Save(TxRecord{ID: "failed", Status: TxFailed, Nonce: 2})
+	j.Save(TxRecord{ID: "pending", Status: TxSigned, Nonce: 3})
+	cutoff := time.Now()
+	j.Save(TxRecord{ID: "recent", Status: TxFinished, Nonce: 4})
+
+	if err := j.Compact(nil); err != nil {
+		t.Fatal(err)
+	}
+	if lines := journalLines(t, path); lines != 4 {
+		t.Fatalf("compacted journal has %d lines, want one per record", lines)
+	}
+
+	if err := j.Compact(UpdatedSince(cutoff)); err != nil {
+		t.Fatal(err)
+	}
+	if _, ok := j.Get("old"); ok {
+		t.Fatal("settled record before the cutoff was kept")
+	}
+	// saves after compaction land in the new file
+	if err := j.Save(TxRecord{ID: "next", Status: TxSigned, Nonce: 5}); err != nil {
+		t.Fatal(err)
+	}
+	j.Close()
+
+	j, err = OpenFileTxJournal(path)
+	if err != nil {
+		t.Fatal(err)
+	}
+	for id, want := range map[string]bool{"old": false, "failed": false, "pending": true, "recent": true, "next": true} {
+		if _, ok := j.Get(id); ok != want {
+			t.Errorf("record %s present = %v, want %v", id, ok, want)
+		}
+	}
+	unfinished, _ := j.Unfinished()
+	if len(unfinished) != 2 || unfinished[0].ID != "pending" || unfinished[1].ID != "next" {
+		t.Fatalf("unfinished = %+v, want pending then next", unfinished)
+	}
+	if entries, _ := os.ReadDir(filepath.Dir(path)); len(entries) != 1 {
+		t.Fatalf("left %d files behind, want only the journal", len(entries))
+	}
+}
+
+func journalLines(t *testing.T, path string) int {
+	t.Helper()
+	data, err := os.ReadFile(path)
+	if err != nil {
+		t.Fatal(err)
+	}
+	return len(strings.Split(strings.TrimSpace(string(data)), "\n"))
+}
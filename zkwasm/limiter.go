@@ -0,0 +1,337 @@
+package zkwasm
+
+import (
+	"context"
+	"io"
+	"net/http"
+	"sync"
+	"time"
+)
+
+// maxKeyBuckets bounds the per-key limiter map; idle buckets are dropped
+// once it is exceeded
+const maxKeyBuckets = 10000
+
+// RateLimit is a token bucket refilled at Rate requests per second holding
+// at most Burst tokens. A Rate of zero or less sets no limit.
+type RateLimit struct {
+	Rate  float64
+	Burst int
+}
+
+// WithRateLimit limits the requests of the client as a whole
+func WithRateLimit(limit RateLimit) Option {
+	return func(rpc *ZKWasmAppRpc) {
+		if limit.Rate <= 0 {
+			return
+		}
+		rpc.ensureLimiter().global = newTokenBucket(limit)
+	}
+}
+
+// WithKeyRateLimit limits the /query and /send requests of each player key
+// separately, on top of any global limit
+func WithKeyRateLimit(limit RateLimit) Option {
+	return func(rpc *ZKWasmAppRpc) {
+		if limit.Rate <= 0 {
+			return
+		}
+		l := rpc.ensureLimiter()
+		l.keyLimit = &limit
+		l.keys = make(map[string]*tokenBucket)
+	}
+}
+
+// WithMaxInFlight bounds the number of concurrent HTTP requests. An n of
+// zero or less sets no bound.
+func WithMaxInFlight(n int) Option {
+	return func(rpc *ZKWasmAppRpc) {
+		if n <= 0 {
+			return
+		}
+		rpc.ensureLimiter().inflight = make(chan struct{}, n)
+	}
+}
+
+// LimiterStats reports how long callers were held back by the client
+// limits and by server 429 responses. The same measurements reach the
+// client Metrics through ObserveLimiterWait and ObserveThrottled.
+type LimiterStats struct {
+	// Requests counts requests that went through the limiter
+	Requests int64
+	// Waits counts requests that had to wait
+	Waits     int64
+	TotalWait time.Duration
+	MaxWait   time.Duration
+	// Throttled counts 429 responses from the server
+	Throttled int64
+}
+
+type tokenBucket struct {
+	rate   float64
+	burst  float64
+	tokens float64
+	last   time.Time
+}
+
+func newTokenBucket(limit RateLimit) *tokenBucket {
+	burst := float64(limit.Burst)
+	if burst < 1 {
+		burst = 1
+	}
+	return &tokenBucket{rate: limit.Rate, burst: burst, tokens: burst, last: time.Now()}
+}
+
+// reserve takes a token and returns how long the caller must wait for it
+func (b *tokenBucket) reserve(now time.Time) time.Duration {
+	b.tokens += now.Sub(b.last).Seconds() * b.rate
+	if b.tokens > b.burst {
+		b.tokens = b.burst
+	}
+	b.last = now
+	b.tokens--
+	if b.tokens >= 0 {
+		return 0
+	}
+	return time.Duration(-b.tokens / b.rate * float64(time.Second))
+}
+
+// refund returns a token taken by a request that was abandoned
+func (b *tokenBucket) refund() {
+	b.tokens++
+	if b.tokens > b.burst {
+		b.tokens = b.burst
+	}
+}
+
+// full reports whether the bucket has refilled completely
+func (b *tokenBucket) full(now time.Time) bool {
+	return b.tokens+now.Sub(b.last).Seconds()*b.rate >= b.burst
+}
+
+type limiter struct {
+	mu       sync.Mutex
+	global   *tokenBucket
+	keyLimit *RateLimit
+	keys     map[string]*tokenBucket
+	inflight chan struct{}
+	stats    LimiterStats
+}
+
+// throttle holds every request of a client until the Retry-After of the
+// last 429 response. Unlike the limiter it is always on.
+type throttle struct {
+	mu           sync.Mutex
+	blockedUntil time.Time
+}
+
+// remaining returns how long requests are still blocked
+func (t *throttle) remaining() time.Duration {
+	t.mu.Lock()
+	defer t.mu.Unlock()
+	return time.Until(t.blockedUntil)
+}
+
+// wait sleeps until the block is over, returning how long it waited
+func (t *throttle) wait(ctx context.Context) (time.Duration, error) {
+	delay := t.remaining()
+	if delay <= 0 {
+		return 0, nil
+	}
+	start := time.Now()
+	timer := time.NewTimer(delay)
+	defer timer.Stop()
+	select {
+	case <-ctx.Done():
+		return time.Since(start), ctx.Err()
+	case <-timer.C:
+		return time.Since(start), nil
+	}
+}
+
+// observe blocks requests until the Retry-After of a 429 response,
+// reporting whether resp was one
+func (t *throttle) observe(resp *http.Response) bool {
+	if resp.StatusCode != http.StatusTooManyRequests {
+		return false
+	}
+	wait := parseRetryAfter(resp.Header.Get("Retry-After"))
+	if wait == 0 {
+		wait = time.Second
+	}
+	t.mu.Lock()
+	defer t.mu.Unlock()
+	if until := time.Now().Add(wait); until.After(t.blockedUntil) {
+		t.blockedUntil = until
+	}
+	return true
+}
+
+func (rpc *ZKWasmAppRpc) ensureLimiter() *limiter {
+	if rpc.limiter == nil {
+		rpc.limiter = &limiter{}
+	}
+	return rpc.limiter
+}
+
+// LimiterStats returns the waiting statistics of the client limits
+func (rpc *ZKWasmAppRpc) LimiterStats() LimiterStats {
+	if rpc.limiter == nil {
+		return LimiterStats{}
+	}
+	rpc.limiter.mu.Lock()
+	defer rpc.limiter.mu.Unlock()
+	return rpc.limiter.stats
+}
+
+// acquire waits for the rate limits of key, for blocked to pass and for a
+// free in-flight slot, returning how long it waited. The returned release
+// must be called once the request completes. Tokens are given back when
+// the context ends during the rate wait; once that wait is over the slot
+// the token paid for has passed, so a context ending while waiting for an
+// in-flight slot keeps it.
+func (l *limiter) acquire(ctx context.Context, key string, blocked time.Duration) (func(), time.Duration, error) {
+	start := time.Now()
+	var reserved []*tokenBucket
+	l.mu.Lock()
+	delay := blocked
+	if l.global != nil {
+		reserved = append(reserved, l.global)
+		if d := l.global.reserve(start); d > delay {
+			delay = d
+		}
+	}
+	if l.keyLimit != nil && key != "" {
+		bucket, ok := l.keys[key]
+		if !ok {
+			l.evictIdle(start)
+			bucket = newTokenBucket(*l.keyLimit)
+			l.keys[key] = bucket
+		}
+		reserved = append(reserved, bucket)
+		if d := bucket.reserve(start); d > delay {
+			delay = d
+		}
+	}
+	l.mu.Unlock()
+
+	abandon := func() (func(), time.Duration, error) {
+		l.mu.Lock()
+		defer l.mu.Unlock()
+		for _, bucket := range reserved {
+			bucket.refund()
+		}
+		return nil, time.Since(start), ctx.Err()
+	}
+	if delay > 0 {
+		timer := time.NewTimer(delay)
+		select {
+		case <-ctx.Done():
+			timer.Stop()
+			return abandon()
+		case <-timer.C:
+		}
+	}
+	release := func() {}
+	if l.inflight != nil {
+		select {
+		case <-ctx.Done():
+			return nil, time.Since(start), ctx.Err()
+		case l.inflight <- struct{}{}:
+		}
+		release = func() { <-l.inflight }
+	}
+
+	waited := time.Since(start)
+	l.mu.Lock()
+	l.stats.Requests++
+	if waited > time.Millisecond {
+		l.stats.Waits++
+		l.stats.TotalWait += waited
+		if waited > l.stats.MaxWait {
+			l.stats.MaxWait = waited
+		}
+	}
+	l.mu.Unlock()
+	return release, waited, nil
+}
+
+func (l *limiter) evictIdle(now time.Time) {
+	if len(l.keys) < maxKeyBuckets {
+		return
+	}
+	for key, bucket := range l.keys {
+		if bucket.full(now) {
+			delete(l.keys, key)
+		}
+	}
+}
+
+// throttled counts a 429 response in the limiter statistics
+func (l *limiter) throttled() {
+	l.mu.Lock()
+	defer l.mu.Unlock()
+	l.stats.Throttled++
+}
+
+// roundTrip performs req through the client limits and any 429 block,
+// propagates the trace context and reports it to the client metrics. key
+// identifies the player for per-key limits and may be empty.
+func (rpc *ZKWasmAppRpc) roundTrip(req *http.Request, endpoint, key string) (*http.Response, error) {
+	release := func() {}
+	var waited time.Duration
+	var err error
+	if rpc.limiter != nil {
+		release, waited, err = rpc.limiter.acquire(req.Context(), key, rpc.throttle.remaining())
+	} else {
+		waited, err = rpc.throttle.wait(req.Context())
+	}
+	if rpc.metrics != nil && (rpc.limiter != nil || waited > 0) {
+		rpc.metrics.ObserveLimiterWait(waited)
+	}
+	if err != nil {
+		return nil, err
+	}
+	resp, err := rpc.transport(req, endpoint)
+	if err != nil {
+		release()
+		return nil, err
+	}
+	if rpc.throttle.observe(resp) {
+		if rpc.limiter != nil {
+			rpc.limiter.throttled()
+		}
+		if rpc.metrics != nil {
+			rpc.metrics.ObserveThrottled()
+		}
+	}
+	// the in-flight slot is held until the body is closed
+	resp.Body = &releaseBody{ReadCloser: resp.Body, release: release}
+	return resp, nil
+}
+
+func (rpc *ZKWasmAppRpc) transport(req *http.Request, endpoint string) (*http.Response, error) {
+	injectTraceparent(req)
+	start := time.Now()
+	resp, err := rpc.client.Do(req)
+	if rpc.metrics != nil {
+		status := 0
+		if err == nil {
+			status = resp.StatusCode
+		}
+		rpc.metrics.ObserveRequest(endpoint, status, time.Since(start))
+	}
+	return resp, err
+}
+
+type releaseBody struct {
+	io.ReadCloser
+	once    sync.Once
+	release func()
+}
+
+func (b *releaseBody) Close() error {
+	err := b.ReadCloser.Close()
+	b.once.Do(b.release)
+	return err
+}
@@ -0,0 +1,167 @@
+package zkwasm
+
+import (
+	"context"
+	"errors"
+	"net/http"
+	"net/http/httptest"
+	"sync"
+	"testing"
+	"time"
+)
+
+func TestLimiterOptionsWithoutLimit(t *testing.T) {
+	for _, opt := range []Option{
+		WithMaxInFlight(0),
+		WithMaxInFlight(-1),
+		WithRateLimit(RateLimit{}),
+		WithKeyRateLimit(RateLimit{Rate: -1, Burst: 5}),
+	} {
+		if rpc := NewZKWasmAppRpc("http://localhost", opt); rpc.limiter != nil {
+			t.Fatalf("limiter = %+v, want none", rpc.limiter)
+		}
+	}
+
+	fake, server := newFakeRollup(t)
+	rpc := NewZKWasmAppRpc(server.URL, WithMaxInFlight(0), WithRetry(fastRetry))
+	if _, err := rpc.SendTransactionWithSigner(context.Background(), NewKeySigner("1234"), testCommand(0)); err != nil {
+		t.Fatal(err)
+	}
+	if fake.appliedCount() != 1 {
+		t.Fatalf("applied %d times", fake.appliedCount())
+	}
+}
+
+func TestLimiterRefundsCancelledReservation(t *testing.T) {
+	l := &limiter{global: newTokenBucket(RateLimit{Rate: 0.001, Burst: 1})}
+	release, _, err := l.acquire(context.Background(), "", 0)
+	if err != nil {
+		t.Fatal(err)
+	}
+	release()
+
+	ctx, cancel := context.WithCancel(context.Background())
+	cancel()
+	for i := 0; i < 3; i++ {
+		if _, _, err := l.acquire(ctx, "", 0); err != context.Canceled {
+			t.Fatalf("err = %v, want context.Canceled", err)
+		}
+	}
+	// without refunds every cancelled caller would push the next wait out
+	// by another 1000 seconds
+	if l.global.tokens < -0.5 {
+		t.Fatalf("tokens = %v after cancelled reservations, want 0", l.global.tokens)
+	}
+}
+
+func TestLimiterKeepsTokenOfCancelledInFlightWait(t *testing.T) {
+	l := &limiter{global: newTokenBucket(RateLimit{Rate: 0.001, Burst: 2}), inflight: make(chan struct{}, 1)}
+	release, _, err := l.acquire(context.Background(), "", 0)
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer release()
+
+	ctx, cancel := context.WithCancel(context.Background())
+	cancel()
+	// the second token is free, so only the in-flight slot is waited for
+	if _, _, err := l.acquire(ctx, "", 0); err != context.Canceled {
+		t.Fatalf("err = %v, want context.Canceled", err)
+	}
+	if l.global.tokens > 0.5 {
+		t.Fatalf("tokens = %v, want the token kept by the cancelled caller", l.global.tokens)
+	}
+}
+
+// throttlingServer answers the first request with a 429 asking to retry
+// after a second and the rest with an empty state, recording when each
+// request arrived
+type throttlingServer struct {
+	mu       sync.Mutex
+	arrivals []time.Time
+	always   bool
+}
+
+func (s *throttlingServer) ServeHTTP(w http.ResponseWriter, r *http.Request) {
+	s.mu.Lock()
+	s.arrivals = append(s.arrivals, time.Now())
+	first := len(s.arrivals) == 1
+	s.mu.Unlock()
+	if first || s.always {
+		w.Header().Set("Retry-After", "1")
+		http.Error(w, "slow down", http.StatusTooManyRequests)
+		return
+	}
+	reply(w, map[string]interface{}{"success": true, "data": "{}"})
+}
+
+func (s *throttlingServer) gap(t *testing.T) time.Duration {
+	t.Helper()
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	if len(s.arrivals) < 2 {
+		t.Fatalf("%d requests arrived, want 2", len(s.arrivals))
+	}
+	return s.arrivals[1].Sub(s.arrivals[0])
+}
+
+func TestThrottleHonouredWithoutLimiter(t *testing.T) {
+	throttling := &throttlingServer{}
+	server := httptest.NewServer(throttling)
+	defer server.Close()
+	rpc := NewZKWasmAppRpc(server.URL)
+
+	if _, err := rpc.QueryStatePkx(context.Background(), "1"); err == nil {
+		t.Fatal("a 429 response succeeded")
+	}
+	if _, err := rpc.QueryStatePkx(context.Background(), "1"); err != nil {
+		t.Fatal(err)
+	}
+	if gap := throttling.gap(t); gap < 900*time.Millisecond {
+		t.Fatalf("second request sent %v after the 429, want the 1s Retry-After", gap)
+	}
+}
+
+func TestRetryDefersToThrottle(t *testing.T) {
+	for _, opts := range [][]Option{nil, {WithMaxInFlight(4), WithRateLimit(RateLimit{Rate: 100, Burst: 10})}} {
+		throttling := &throttlingServer{always: true}
+		server := httptest.NewServer(throttling)
+		policy := RetryPolicy{MaxAttempts: 2, InitialBackoff: time.Millisecond, Multiplier: 1}
+		rpc := NewZKWasmAppRpc(server.URL, append(opts, WithRetry(policy))...)
+
+		_, err := rpc.SendTransactionWithSigner(context.Background(), NewKeySigner("1234"), testCommand(0))
+		server.Close()
+		var retryErr *RetryError
+		if !errors.As(err, &retryErr) || len(retryErr.Attempts) != 2 {
+			t.Fatalf("err = %v, want two throttled attempts", err)
+		}
+		if backoff := retryErr.Attempts[0].Backoff; backoff != time.Millisecond {
+			t.Fatalf("backoff = %v, want the policy backoff with the Retry-After left to the throttle", backoff)
+		}
+		// waited once: neither back to back nor twice the Retry-After
+		if gap := throttling.gap(t); gap < 900*time.Millisecond || gap > 1500*time.Millisecond {
+			t.Fatalf("retry sent %v after the 429, want about the 1s Retry-After", gap)
+		}
+	}
+}
+
+func TestLimiterReportsMetrics(t *testing.T) {
+	server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		w.Header().Set("Retry-After", "1")
+		http.Error(w, "slow down", http.StatusTooManyRequests)
+	}))
+	defer server.Close()
+	metrics := NewMetricsRecorder()
+	rpc := NewZKWasmAppRpc(server.URL, WithMaxInFlight(1), WithMetrics(metrics))
+
+	if _, err := rpc.QueryStatePkx(context.Background(), "1"); err == nil {
+		t.Fatal("a 429 response succeeded")
+	}
+	snapshot := metrics.Snapshot()
+	if snapshot.LimiterWait.Count != 1 || snapshot.Throttled != 1 {
+		t.Fatalf("limiter waits = %d, throttled = %d, want 1 and 1", snapshot.LimiterWait.Count, snapshot.Throttled)
+	}
+	if stats := rpc.LimiterStats(); stats.Requests != 1 || stats.Throttled != 1 {
+		t.Fatalf("stats = %+v", stats)
+	}
+}
@@ -0,0 +1,210 @@
+package zkwasm
+
+import (
+	"sync"
+	"time"
+)
+
+// Endpoint names reported to Metrics
+const (
+	EndpointSend   = "/send"
+	EndpointQuery  = "/query"
+	EndpointConfig = "/config"
+	EndpointJob    = "/job"
+)
+
+// Metrics receives measurements from a ZKWasmAppRpc. MetricsRecorder is
+// the bundled implementation; other backends can implement the interface
+// directly.
+type Metrics interface {
+	// ObserveRequest records an HTTP request; status is 0 when the request
+	// failed before a response arrived
+	ObserveRequest(endpoint string, status int, latency time.Duration)
+	// ObserveJob records how long a transaction job was waited for; err is
+	// nil when the job finished. ClassifyError maps err to a FailureKind
+	// for backends that need a bounded label.
+	ObserveJob(wait time.Duration, err error)
+	// ObserveRetry records a transaction retry caused by kind
+	ObserveRetry(kind FailureKind)
+	// ObserveSign records the time spent signing a transaction
+	ObserveSign(latency time.Duration)
+	// ObserveLimiterWait records how long a request waited for the client
+	// rate limits and in-flight bound
+	ObserveLimiterWait(wait time.Duration)
+	// ObserveThrottled records a 429 response from the server
+	ObserveThrottled()
+}
+
+// WithMetrics reports request, job, retry and signing measurements to m
+func WithMetrics(m Metrics) Option {
+	return func(rpc *ZKWasmAppRpc) {
+		rpc.metrics = m
+	}
+}
+
+// DefaultLatencyBuckets are the histogram upper bounds in seconds
+var DefaultLatencyBuckets = []float64{0.005, 0.01, 0.025, 0.05, 0.1, 0.25, 0.5, 1, 2.5, 5, 10}
+
+// HistogramSnapshot is a cumulative latency histogram
+type HistogramSnapshot struct {
+	// Bounds are the bucket upper bounds in seconds
+	Bounds []float64
+	// Counts are the cumulative observation counts per bound
+	Counts []uint64
+	Count  uint64
+	// Sum is the total of the observations in seconds
+	Sum float64
+}
+
+type histogram struct {
+	bounds []float64
+	counts []uint64
+	count  uint64
+	sum    float64
+}
+
+func newHistogram() *histogram {
+	return &histogram{bounds: DefaultLatencyBuckets, counts: make([]uint64, len(DefaultLatencyBuckets))}
+}
+
+func (h *histogram) observe(d time.Duration) {
+	v := d.Seconds()
+	for i, bound := range h.bounds {
+		if v <= bound {
+			h.counts[i]++
+		}
+	}
+	h.count++
+	h.sum += v
+}
+
+func (h *histogram) snapshot() HistogramSnapshot {
+	return HistogramSnapshot{
+		Bounds: append([]float64{}, h.bounds...),
+		Counts: append([]uint64{}, h.counts...),
+		Count:  h.count,
+		Sum:    h.sum,
+	}
+}
+
+// RequestKey identifies a request counter
+type RequestKey struct {
+	Endpoint string
+	Status   int
+}
+
+// MetricsSnapshot is a point-in-time copy of a MetricsRecorder
+type MetricsSnapshot struct {
+	Requests       map[RequestKey]uint64
+	RequestLatency map[string]HistogramSnapshot
+	JobWait        HistogramSnapshot
+	// JobFailures counts failed jobs by FailureKind name
+	JobFailures map[string]uint64
+	Retries     map[string]uint64
+	SignLatency HistogramSnapshot
+	LimiterWait HistogramSnapshot
+	Throttled   uint64
+}
+
+// MetricsRecorder keeps measurements in memory. Snapshot copies them for
+// tests and exporters; the zkwasmprom module exposes them to Prometheus.
+type MetricsRecorder struct {
+	mu             sync.Mutex
+	requests       map[RequestKey]uint64
+	requestLatency map[string]*histogram
+	jobWait        *histogram
+	jobFailures    map[string]uint64
+	retries        map[string]uint64
+	signLatency    *histogram
+	limiterWait    *histogram
+	throttled      uint64
+}
+
+// NewMetricsRecorder creates an empty recorder
+func NewMetricsRecorder() *MetricsRecorder {
+	return &MetricsRecorder{
+		requests:       make(map[RequestKey]uint64),
+		requestLatency: make(map[string]*histogram),
+		jobWait:        newHistogram(),
+		jobFailures:    make(map[string]uint64),
+		retries:        make(map[string]uint64),
+		signLatency:    newHistogram(),
+		limiterWait:    newHistogram(),
+	}
+}
+
+func (m *MetricsRecorder) ObserveRequest(endpoint string, status int, latency time.Duration) {
+	m.mu.Lock()
+	defer m.mu.Unlock()
+	m.requests[RequestKey{Endpoint: endpoint, Status: status}]++
+	h, ok := m.requestLatency[endpoint]
+	if !ok {
+		h = newHistogram()
+		m.requestLatency[endpoint] = h
+	}
+	h.observe(latency)
+}
+
+func (m *MetricsRecorder) ObserveJob(wait time.Duration, err error) {
+	m.mu.Lock()
+	defer m.mu.Unlock()
+	m.jobWait.observe(wait)
+	if err != nil {
+		// the server's failedReason is free text, so it is reduced to its
+		// kind to keep the label set bounded
+		m.jobFailures[ClassifyError(err).String()]++
+	}
+}
+
+func (m *MetricsRecorder) ObserveRetry(kind FailureKind) {
+	m.mu.Lock()
+	defer m.mu.Unlock()
+	m.retries[kind.String()]++
+}
+
+func (m *MetricsRecorder) ObserveSign(latency time.Duration) {
+	m.mu.Lock()
+	defer m.mu.Unlock()
+	m.signLatency.observe(latency)
+}
+
+func (m *MetricsRecorder) ObserveLimiterWait(wait time.Duration) {
+	m.mu.Lock()
+	defer m.mu.Unlock()
+	m.limiterWait.observe(wait)
+}
+
+func (m *MetricsRecorder) ObserveThrottled() {
+	m.mu.Lock()
+	defer m.mu.Unlock()
+	m.throttled++
+}
+
+// Snapshot copies the current measurements
+func (m *MetricsRecorder) Snapshot() MetricsSnapshot {
+	m.mu.Lock()
+	defer m.mu.Unlock()
+	snapshot := MetricsSnapshot{
+		Requests:       make(map[RequestKey]uint64),
+		RequestLatency: make(map[string]HistogramSnapshot),
+		JobWait:        m.jobWait.snapshot(),
+		JobFailures:    make(map[string]uint64),
+		Retries:        make(map[string]uint64),
+		SignLatency:    m.signLatency.snapshot(),
+		LimiterWait:    m.limiterWait.snapshot(),
+		Throttled:      m.throttled,
+	}
+	for k, v := range m.requests {
+		snapshot.Requests[k] = v
+	}
+	for k, h := range m.requestLatency {
+		snapshot.RequestLatency[k] = h.snapshot()
+	}
+	for k, v := range m.jobFailures {
+		snapshot.JobFailures[k] = v
+	}
+	for k, v := range m.retries {
+		snapshot.Retries[k] = v
+	}
+	return snapshot
+}
@@ -0,0 +1,53 @@
+package zkwasm
+
+import (
+	"context"
+	"testing"
+)
+
+func TestMetricsRecorderSnapshot(t *testing.T) {
+	_, server := newFakeRollup(t)
+	metrics := NewMetricsRecorder()
+	rpc := NewZKWasmAppRpc(server.URL, WithMetrics(metrics))
+	if _, err := rpc.SendTransactionWithSigner(context.Background(), NewKeySigner("1234"), testCommand(0)); err != nil {
+		t.Fatal(err)
+	}
+
+	s := metrics.Snapshot()
+	if s.Requests[RequestKey{Endpoint: EndpointSend, Status: 201}] != 1 {
+		t.Fatalf("requests = %v, want one /send", s.Requests)
+	}
+	if s.RequestLatency[EndpointJob].Count == 0 {
+		t.Fatal("job polls were not timed")
+	}
+	if s.JobWait.Count != 1 || s.SignLatency.Count != 1 || len(s.JobFailures) != 0 {
+		t.Fatalf("job waits = %d, signs = %d, failures = %v", s.JobWait.Count, s.SignLatency.Count, s.JobFailures)
+	}
+
+	// the snapshot is a copy
+	s.Requests[RequestKey{Endpoint: EndpointSend, Status: 201}] = 7
+	s.JobWait.Counts[0] = 7
+	if again := metrics.Snapshot(); again.Requests[RequestKey{Endpoint: EndpointSend, Status: 201}] != 1 || again.JobWait.Counts[0] == 7 {
+		t.Fatal("snapshot shares state with the recorder")
+	}
+}
+
+func TestMetricsJobFailuresBounded(t *testing.T) {
+	metrics := NewMetricsRecorder()
+	for _, reason := range []string{"player 1 not found", "player 2 not found", "invalid nonce 3"} {
+		metrics.ObserveJob(0, &JobFailedError{JobID: "1", Reason: reason})
+	}
+	metrics.ObserveJob(0, ErrMonitorTransactionFail)
+
+	s := metrics.Snapshot()
+	want := map[string]uint64{"application": 2, "nonce": 1, "timeout": 1}
+	if len(s.JobFailures) != len(want) {
+		t.Fatalf("job failures = %v, want %v", s.JobFailures, want)
+	}
+	for reason, n := range want {
+		if s.JobFailures[reason] != n {
+			t.Fatalf("job failures = %v, want %v", s.JobFailures, want)
+		}
+	}
+
+}
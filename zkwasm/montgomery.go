@@ -0,0 +1,332 @@
+package zkwasm
+
+import (
+	"errors"
+	"math/big"
+	"math/bits"
+)
+
+var ErrInvalidFieldEncoding = errors.New("InvalidFieldEncoding")
+
+// limbs is a 256-bit value as four little-endian 64-bit words
+type limbs [4]uint64
+
+// montgomery is arithmetic modulo an odd p below 2^255 on values kept in
+// Montgomery form a*R mod p with R = 2^256. Add, sub, neg and mul run in
+// constant time; exp and inv are constant time in the base only, and
+// legendre, sqrt and the math/big conversions are variable time.
+type montgomery struct {
+	p limbs
+	// pInv is -p^-1 mod 2^64
+	pInv uint64
+	// r2 is R^2 mod p, used to enter Montgomery form
+	r2 limbs
+	// one is R mod p, the form of 1
+	one     limbs
+	modulus *big.Int
+
+	// Tonelli-Shanks constants for p-1 = q*2^s: q, (q+1)/2, (p-1)/2 and
+	// z^q for a quadratic non-residue z
+	s         int
+	q         []uint64
+	qHalf     []uint64
+	pHalf     []uint64
+	rootOfOne limbs
+}
+
+func newMontgomery(decimal string) *montgomery {
+	modulus, ok := new(big.Int).SetString(decimal, 10)
+	if !ok {
+		panic("invalid modulus")
+	}
+	m := &montgomery{modulus: modulus, p: bigToLimbs(modulus)}
+	// Newton iteration for p^-1 mod 2^64
+	inv := uint64(1)
+	for i := 0; i < 6; i++ {
+		inv *= 2 - m.p[0]*inv
+	}
+	m.pInv = -inv
+	r := new(big.Int).Lsh(big.NewInt(1), 256)
+	m.one = bigToLimbs(new(big.Int).Mod(r, modulus))
+	m.r2 = bigToLimbs(new(big.Int).Mod(new(big.Int).Mul(r, r), modulus))
+
+	pMinus1 := new(big.Int).Sub(modulus, big.NewInt(1))
+	q := new(big.Int).Set(pMinus1)
+	for q.Bit(0) == 0 {
+		q.Rsh(q, 1)
+		m.s++
+	}
+	m.q = bigToWords(q)
+	m.qHalf = bigToWords(new(big.Int).Rsh(new(big.Int).Add(q, big.NewInt(1)), 1))
+	m.pHalf = bigToWords(new(big.Int).Rsh(pMinus1, 1))
+	for z := int64(2); ; z++ {
+		if big.Jacobi(big.NewInt(z), modulus) == -1 {
+			zm := m.fromBig(big.NewInt(z))
+			m.rootOfOne = m.exp(&zm, m.q)
+			break
+		}
+	}
+	return m
+}
+
+// bigToWords splits a non-negative v into little-endian 64-bit words
+func bigToWords(v *big.Int) []uint64 {
+	buf := v.Bytes()
+	words := make([]uint64, (len(buf)+7)/8)
+	for i, b := range buf {
+		shift := len(buf) - 1 - i
+		words[shift/8] |= uint64(b) << (8 * (shift % 8))
+	}
+	return words
+}
+
+// bigToLimbs converts a non-negative v below 2^256
+func bigToLimbs(v *big.Int) limbs {
+	var buf [32]byte
+	v.FillBytes(buf[:])
+	return bytesToLimbs(&buf)
+}
+
+// bytesToLimbs reads a 32-byte big-endian value
+func bytesToLimbs(buf *[32]byte) limbs {
+	var out limbs
+	for i := 0; i < 4; i++ {
+		for j := 0; j < 8; j++ {
+			out[i] |= uint64(buf[31-8*i-j]) << (8 * j)
+		}
+	}
+	return out
+}
+
+// bytes writes a as 32 big-endian bytes
+func (a *limbs) bytes() [32]byte {
+	var out [32]byte
+	for i := 0; i < 4; i++ {
+		for j := 0; j < 8; j++ {
+			out[31-8*i-j] = byte(a[i] >> (8 * j))
+		}
+	}
+	return out
+}
+
+func (a *limbs) isZero() bool {
+	return a[0]|a[1]|a[2]|a[3] == 0
+}
+
+// fromBig returns v mod p in Montgomery form
+func (m *montgomery) fromBig(v *big.Int) limbs {
+	reduced := new(big.Int).Mod(v, m.modulus)
+	plain := bigToLimbs(reduced)
+	return m.mul(&plain, &m.r2)
+}
+
+// reduce returns the canonical value of a leaving Montgomery form
+func (m *montgomery) reduce(a *limbs) limbs {
+	return m.mul(a, &limbs{1})
+}
+
+func (m *montgomery) toBig(a *limbs) *big.Int {
+	plain := m.reduce(a)
+	buf := plain.bytes()
+	return new(big.Int).SetBytes(buf[:])
+}
+
+// subtractP returns t - p when t >= p, and t otherwise. carry is a fifth,
+// most significant word of t.
+func (m *montgomery) subtractP(t *limbs, carry uint64) limbs {
+	var r limbs
+	var borrow uint64
+	r[0], borrow = bits.Sub64(t[0], m.p[0], 0)
+	r[1], borrow = bits.Sub64(t[1], m.p[1], borrow)
+	r[2], borrow = bits.Sub64(t[2], m.p[2], borrow)
+	r[3], borrow = bits.Sub64(t[3], m.p[3], borrow)
+	_, borrow = bits.Sub64(carry, 0, borrow)
+	// keep t when the subtraction borrowed
+	mask := -borrow
+	for i := range r {
+		r[i] = (t[i] & mask) | (r[i] &^ mask)
+	}
+	return r
+}
+
+func (m *montgomery) add(a, b *limbs) limbs {
+	var t limbs
+	var carry uint64
+	t[0], carry = bits.Add64(a[0], b[0], 0)
+	t[1], carry = bits.Add64(a[1], b[1], carry)
+	t[2], carry = bits.Add64(a[2], b[2], carry)
+	t[3], carry = bits.Add64(a[3], b[3], carry)
+	return m.subtractP(&t, carry)
+}
+
+func (m *montgomery) sub(a, b *limbs) limbs {
+	var t limbs
+	var borrow uint64
+	t[0], borrow = bits.Sub64(a[0], b[0], 0)
+	t[1], borrow = bits.Sub64(a[1], b[1], borrow)
+	t[2], borrow = bits.Sub64(a[2], b[2], borrow)
+	t[3], borrow = bits.Sub64(a[3], b[3], borrow)
+	// add p back when the subtraction borrowed
+	mask := -borrow
+	var carry uint64
+	t[0], carry = bits.Add64(t[0], m.p[0]&mask, 0)
+	t[1], carry = bits.Add64(t[1], m.p[1]&mask, carry)
+	t[2], carry = bits.Add64(t[2], m.p[2]&mask, carry)
+	t[3], _ = bits.Add64(t[3], m.p[3]&mask, carry)
+	return t
+}
+
+func (m *montgomery) neg(a *limbs) limbs {
+	return m.sub(&limbs{}, a)
+}
+
+// mul is the CIOS Montgomery product a*b/R mod p
+func (m *montgomery) mul(a, b *limbs) limbs {
+	var t [6]uint64
+	for i := 0; i < 4; i++ {
+		var c uint64
+		for j := 0; j < 4; j++ {
+			hi, lo := bits.Mul64(a[j], b[i])
+			var cc uint64
+			lo, cc = bits.Add64(lo, t[j], 0)
+			hi += cc
+			lo, cc = bits.Add64(lo, c, 0)
+			hi += cc
+			t[j], c = lo, hi
+		}
+		var cc uint64
+		t[4], cc = bits.Add64(t[4], c, 0)
+		t[5] = cc
+
+		q := t[0] * m.pInv
+		hi, lo := bits.Mul64(q, m.p[0])
+		_, cc = bits.Add64(lo, t[0], 0)
+		c = hi + cc
+		for j := 1; j < 4; j++ {
+			hi, lo = bits.Mul64(q, m.p[j])
+			lo, cc = bits.Add64(lo, t[j], 0)
+			hi += cc
+			lo, cc = bits.Add64(lo, c, 0)
+			hi += cc
+			t[j-1], c = lo, hi
+		}
+		t[3], cc = bits.Add64(t[4], c, 0)
+		t[4] = t[5] + cc
+	}
+	r := limbs{t[0], t[1], t[2], t[3]}
+	return m.subtractP(&r, t[4])
+}
+
+func (m *montgomery) square(a *limbs) limbs {
+	return m.mul(a, a)
+}
+
+// exp raises a to the plain (not Montgomery) exponent e given as
+// little-endian words. It branches on the bits of e, so e must be public;
+// a may be secret.
+func (m *montgomery) exp(a *limbs, e []uint64) limbs {
+	result := m.one
+	for i := len(e) - 1; i >= 0; i-- {
+		for bit := 63; bit >= 0; bit-- {
+			result = m.square(&result)
+			if (e[i]>>uint(bit))&1 == 1 {
+				result = m.mul(&result, a)
+			}
+		}
+	}
+	return result
+}
+
+// inv returns a^(p-2): the inverse of a non-zero a, and zero for zero.
+// The exponent is the public p-2, so inverting a secret value, such as
+// the Z of a secret multiple, does not branch on it.
+func (m *montgomery) inv(a *limbs) limbs {
+	e := m.p
+	var borrow uint64
+	e[0], borrow = bits.Sub64(e[0], 2, 0)
+	for i := 1; i < 4; i++ {
+		e[i], borrow = bits.Sub64(e[i], 0, borrow)
+	}
+	return m.exp(a, e[:])
+}
+
+// legendre returns 1 for a non-zero square, -1 for a non-square and 0
+// for zero
+func (m *montgomery) legendre(a *limbs) int {
+	if a.isZero() {
+		return 0
+	}
+	if r := m.exp(a, m.pHalf); r == m.one {
+		return 1
+	}
+	return -1
+}
+
+// sqrt returns a square root of a by Tonelli-Shanks, reporting false when
+// a is not a square
+func (m *montgomery) sqrt(a *limbs) (limbs, bool) {
+	switch m.legendre(a) {
+	case 0:
+		return limbs{}, true
+	case -1:
+		return limbs{}, false
+	}
+	e := m.s
+	c := m.rootOfOne
+	t := m.exp(a, m.q)
+	r := m.exp(a, m.qHalf)
+	for t != m.one {
+		// least i with t^(2^i) = 1
+		i := 0
+		for t2 := t; t2 != m.one; i++ {
+			t2 = m.square(&t2)
+		}
+		b := c
+		for j := 0; j < e-i-1; j++ {
+			b = m.square(&b)
+		}
+		e = i
+		c = m.square(&b)
+		t = m.mul(&t, &c)
+		r = m.mul(&r, &b)
+	}
+	return r, true
+}
+
+// decode reads 32 bytes, little-endian when le is set, into Montgomery
+// form. Values not below p are rejected.
+func (m *montgomery) decode(b []byte, le bool) (limbs, error) {
+	if len(b) != 32 {
+		return limbs{}, ErrInvalidFieldEncoding
+	}
+	var buf [32]byte
+	copy(buf[:], b)
+	if le {
+		reverseBytes(buf[:])
+	}
+	plain := bytesToLimbs(&buf)
+	// subtracting p only succeeds for a value that is not below p
+	if m.subtractP(&plain, 0) != plain {
+		return limbs{}, ErrInvalidFieldEncoding
+	}
+	return m.mul(&plain, &m.r2), nil
+}
+
+// encode writes the canonical value of a as 32 bytes
+func (m *montgomery) encode(a *limbs, le bool) [32]byte {
+	plain := m.reduce(a)
+	out := plain.bytes()
+	if le {
+		reverseBytes(out[:])
+	}
+	return out
+}
+
+// expBig raises a to e, inverting a for a negative e
+func (m *montgomery) expBig(a *limbs, e *big.Int) limbs {
+	base := *a
+	if e.Sign() < 0 {
+		base = m.inv(a)
+	}
+	return m.exp(&base, bigToWords(new(big.Int).Abs(e)))
+}
@@ -1,10 +1,16 @@
 package zkwasm
 
 import (
+	"errors"
 	"fmt"
 	"math/big"
 )
 
+var (
+	ErrInvalidPoint   = errors.New("InvalidPoint")
+	ErrLengthMismatch = errors.New("LengthMismatch")
+)
+
 // Point struct represents a point on the elliptic curve
 type Point struct {
 	x, y *Field
@@ -22,7 +28,79 @@ func (p *Point) Zero() *Point {
 
 // IsZero checks if the point is the point at infinity
 func (p *Point) IsZero() bool {
-	return p.x.v.Cmp(big.NewInt(0)) == 0 && p.y.v.Cmp(big.NewInt(1)) == 0
+	return p != nil && p.x.m.isZero() && p.y.m == fieldArith.one
+}
+
+// MultiScalarMul computes the sum of scalars[i] * points[i] in one pass
+// with Straus' method: the doublings are shared by all terms, each adding a
+// precomputed multiple of its point per 4-bit digit. Scalars are treated
+// as public. Points and scalars of different lengths are an
+// ErrLengthMismatch; off-curve points can give a sum with no affine form,
+// which is an ErrInvalidPoint.
+func MultiScalarMul(points []*Point, scalars []*Field) (*Point, error) {
+	if len(points) != len(scalars) {
+		return nil, fmt.Errorf("%w: %d points and %d scalars", ErrLengthMismatch, len(points), len(scalars))
+	}
+	if len(points) == 0 {
+		return (&Point{}).Zero(), nil
+	}
+	tables := make([][1 << baseWindowBits]*extPoint, len(points))
+	digits := make([]limbs, len(points))
+	for i, p := range points {
+		ext := p.extended()
+		tables[i][0] = ext.identity()
+		for j := 1; j < 1<<baseWindowBits; j++ {
+			tables[i][j] = tables[i][j-1].add(ext)
+		}
+		digits[i] = fieldArith.reduce(&scalars[i].m)
+	}
+	result := tables[0][0]
+	for w := baseWindows - 1; w >= 0; w-- {
+		for n := 0; n < baseWindowBits; n++ {
+			result = result.double()
+		}
+		for i := range points {
+			digit := (digits[i][w/16] >> (4 * (w % 16))) & (1<<baseWindowBits - 1)
+			if digit != 0 {
+				result = result.add(tables[i][digit])
+			}
+		}
+	}
+	sum, ok := result.affine()
+	if !ok {
+		return nil, fmt.Errorf("%w: sum has no affine form", ErrInvalidPoint)
+	}
+	return sum, nil
+}
+
+// Equal reports whether p and other are the same point
+func (p *Point) Equal(other *Point) bool {
+	if p == nil || other == nil {
+		return false
+	}
+	return p.x.Equal(other.x) && p.y.Equal(other.y)
+}
+
+// IsOnCurve checks a*x^2 + y^2 = 1 + d*x^2*y^2. The nil result of
+// arithmetic on off-curve points is not on the curve.
+func (p *Point) IsOnCurve() bool {
+	if p == nil {
+		return false
+	}
+	x2 := p.x.Square()
+	y2 := p.y.Square()
+	lhs := Constants["a"].Mul(x2).Add(y2)
+	rhs := Constants["d"].Mul(x2).Mul(y2).Add(NewField(big.NewInt(1)))
+	return lhs.Equal(rhs)
+}
+
+// IsInSubgroup checks that p is on the curve and in the prime-order
+// subgroup, that is without a small-order component
+func (p *Point) IsInSubgroup() bool {
+	if !p.IsOnCurve() {
+		return false
+	}
+	return p.Mul(NewField(curveFieldArith.modulus)).IsZero()
 }
 
 // Base returns the base point of the elliptic curve
@@ -32,41 +110,117 @@ func (p *Point) Base() *Point {
 	return NewPoint(gX, gY)
 }
 
-// Add adds two points on the elliptic curve
+// Add adds two points on the elliptic curve. The addition law is complete
+// on the curve; off-curve operands can give a sum with no affine form, and
+// Add then returns nil.
 func (p *Point) Add(other *Point) *Point {
-	// Curve equation: y^2 = x^3 + ax + b (here we use a simplified version)
-	u1 := p.x
-	v1 := p.y
-	u2 := other.x
-	v2 := other.y
+	sum, _ := p.extended().add(other.extended()).affine()
+	return sum
+}
 
-	// Compute the numerator and denominator for x-coordinate
-	u3_m := u1.Mul(v2).Add(v1.Mul(u2))                                                  // u3_m = u1*v2 + v1*u2
-	u3_d := Constants["d"].Mul(u1).Mul(u2).Mul(v1).Mul(v2).Add(NewField(toBigInt("1"))) // u3_d = 1 + d*u1*u2*v1*v2
-	u3 := u3_m.Div(u3_d)                                                                // u3 = u3_m / u3_d
+// Double returns 2 * P, or nil like Add
+func (p *Point) Double() *Point {
+	double, _ := p.extended().double().affine()
+	return double
+}
 
-	// Compute the numerator and denominator for y-coordinate
-	v3_m := v1.Mul(v2).Sub(Constants["a"].Mul(u1).Mul(u2))                              // v3_m = v1*v2 - a*u1*u2
-	v3_d := NewField(toBigInt("1")).Sub(Constants["d"].Mul(u1).Mul(u2).Mul(v1).Mul(v2)) // v3_d = 1 - d*u1*u2*v1*v2
-	v3 := v3_m.Div(v3_d)                                                                // v3 = v3_m / v3_d
+// Neg returns -P, the point (-x, y)
+func (p *Point) Neg() *Point {
+	return NewPoint(p.x.Neg(), p.y)
+}
 
-	return NewPoint(u3, v3)
+// Sub returns P - other
+func (p *Point) Sub(other *Point) *Point {
+	return p.Add(other.Neg())
 }
 
-// Mul performs scalar multiplication (k * P)
+// Mul performs scalar multiplication (k * P), returning nil like Add
 func (p *Point) Mul(k *Field) *Point {
-	// Scalar multiplication: repeated doubling and adding (binary method)
-	result := p.Zero() // start with point at infinity
-	acc := p
+	// double-and-add from the most significant bit, in extended coordinates
+	// so only the final conversion needs an inversion
+	result := p.extended().identity()
+	base := p.extended()
+	scalar := k.BigInt()
+	for i := scalar.BitLen() - 1; i >= 0; i-- {
+		result = result.double()
+		if scalar.Bit(i) == 1 {
+			result = result.add(base)
+		}
+	}
+	product, _ := result.affine()
+	return product
+}
 
-	for i := 0; i < k.v.BitLen(); i++ {
-		if k.v.Bit(i) == 1 {
-			result = result.Add(acc)
+// Compress encodes p in 32 bytes like circomlib packPoint: y in
+// little-endian with the top bit set when x is above (p-1)/2
+func (p *Point) Compress() [32]byte {
+	out := p.y.BytesLE()
+	if fieldIsNegative(p.x) {
+		out[31] |= 0x80
+	}
+	return out
+}
+
+// DecompressPoint decodes a Compress encoding, recovering x from the curve
+// equation. The point is on the curve but may lie outside the subgroup.
+func DecompressPoint(b []byte) (*Point, error) {
+	if len(b) != 32 {
+		return nil, fmt.Errorf("%w: expected 32 bytes, got %d", ErrInvalidPoint, len(b))
+	}
+	buf := make([]byte, 32)
+	copy(buf, b)
+	negative := buf[31]&0x80 != 0
+	buf[31] &= 0x7f
+	y, err := FieldFromBytesLE(buf)
+	if err != nil {
+		return nil, fmt.Errorf("%w: %v", ErrInvalidPoint, err)
+	}
+	// x^2 = (1 - y^2) / (a - d*y^2)
+	y2 := y.Square()
+	one := NewField(big.NewInt(1))
+	den := Constants["a"].Sub(Constants["d"].Mul(y2))
+	if den.IsZero() {
+		return nil, ErrInvalidPoint
+	}
+	x, ok := one.Sub(y2).Div(den).Sqrt()
+	if !ok {
+		return nil, fmt.Errorf("%w: no x for y", ErrInvalidPoint)
+	}
+	if x.IsZero() && negative {
+		return nil, fmt.Errorf("%w: negative zero x", ErrInvalidPoint)
+	}
+	if fieldIsNegative(x) != negative {
+		x = x.Neg()
+	}
+	return NewPoint(x, y), nil
+}
+
+// pointFromX recovers the subgroup point with the given x. Of the two
+// candidates (x, y) and (x, -y) at most one is in the subgroup, as they
+// differ by the point of order 2.
+func pointFromX(x *Field) (*Point, error) {
+	// y^2 = (1 - a*x^2) / (1 - d*x^2)
+	x2 := x.Square()
+	one := NewField(big.NewInt(1))
+	den := one.Sub(Constants["d"].Mul(x2))
+	if den.IsZero() {
+		return nil, ErrInvalidPoint
+	}
+	y, ok := one.Sub(Constants["a"].Mul(x2)).Div(den).Sqrt()
+	if !ok {
+		return nil, fmt.Errorf("%w: no y for x", ErrInvalidPoint)
+	}
+	for _, candidate := range []*Point{NewPoint(x, y), NewPoint(x, y.Neg())} {
+		if candidate.IsInSubgroup() {
+			return candidate, nil
 		}
-		acc = acc.Add(acc) // double the point
 	}
+	return nil, fmt.Errorf("%w: not in the subgroup", ErrInvalidPoint)
+}
 
-	return result
+// fieldIsNegative reports whether f is above (p-1)/2
+func fieldIsNegative(f *Field) bool {
+	return f.BigInt().Cmp(new(big.Int).Rsh(fieldArith.modulus, 1)) > 0
 }
 
 // String returns a string representation of the point (x, y)
@@ -79,3 +233,55 @@ func PointBase() *Point {
 	gY := Constants["gY"]
 	return NewPoint(gX, gY)
 }
+
+// extPoint is a point in extended twisted Edwards coordinates (X:Y:Z:T)
+// with x = X/Z, y = Y/Z and T = XY/Z
+type extPoint struct {
+	x, y, z, t *Field
+}
+
+func (p *Point) extended() *extPoint {
+	return &extPoint{x: p.x, y: p.y, z: NewField(big.NewInt(1)), t: p.x.Mul(p.y)}
+}
+
+func (p *extPoint) identity() *extPoint {
+	return &extPoint{x: NewField(big.NewInt(0)), y: NewField(big.NewInt(1)), z: NewField(big.NewInt(1)), t: NewField(big.NewInt(0))}
+}
+
+// affine converts back to (x, y) with a single inversion, and false when
+// Z is zero, which only off-curve operands produce
+func (p *extPoint) affine() (*Point, bool) {
+	zInv, ok := p.z.InvChecked()
+	if !ok {
+		return nil, false
+	}
+	return NewPoint(p.x.Mul(zInv), p.y.Mul(zInv)), true
+}
+
+// add is the unified add-2008-hwcd formula
+func (p *extPoint) add(q *extPoint) *extPoint {
+	a := p.x.Mul(q.x)
+	b := p.y.Mul(q.y)
+	c := Constants["d"].Mul(p.t).Mul(q.t)
+	d := p.z.Mul(q.z)
+	e := p.x.Add(p.y).Mul(q.x.Add(q.y)).Sub(a).Sub(b)
+	f := d.Sub(c)
+	g := d.Add(c)
+	h := b.Sub(Constants["a"].Mul(a))
+	return &extPoint{x: e.Mul(f), y: g.Mul(h), z: f.Mul(g), t: e.Mul(h)}
+}
+
+// double is the dbl-2008-hwcd formula, cheaper than add(p)
+func (p *extPoint) double() *extPoint {
+	a := p.x.Mul(p.x)
+	b := p.y.Mul(p.y)
+	c := p.z.Mul(p.z)
+	c = c.Add(c)
+	d := Constants["a"].Mul(a)
+	e := p.x.Add(p.y)
+	e = e.Mul(e).Sub(a).Sub(b)
+	g := d.Add(b)
+	f := g.Sub(c)
+	h := d.Sub(b)
+	return &extPoint{x: e.Mul(f), y: g.Mul(h), z: f.Mul(g), t: e.Mul(h)}
+}
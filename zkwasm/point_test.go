@@ -0,0 +1,316 @@
+package zkwasm
+
+import (
+	"errors"
+	"math/big"
+	"math/rand"
+	"testing"
+)
+
+// affine is a reference point in plain affine coordinates over math/big,
+// independent of the extended-coordinate code under test
+type affine struct {
+	x, y *big.Int
+}
+
+func affineAdd(p, q affine) affine {
+	mod := fieldArith.modulus
+	a := Constants["a"].BigInt()
+	d := Constants["d"].BigInt()
+	x1x2 := new(big.Int).Mul(p.x, q.x)
+	y1y2 := new(big.Int).Mul(p.y, q.y)
+	dxy := new(big.Int).Mul(d, x1x2)
+	dxy.Mul(dxy, y1y2).Mod(dxy, mod)
+
+	// x3 = (x1*y2 + y1*x2) / (1 + d*x1*x2*y1*y2)
+	xn := new(big.Int).Mul(p.x, q.y)
+	xn.Add(xn, new(big.Int).Mul(p.y, q.x))
+	xd := new(big.Int).Add(big.NewInt(1), dxy)
+	xd.ModInverse(xd.Mod(xd, mod), mod)
+	// y3 = (y1*y2 - a*x1*x2) / (1 - d*x1*x2*y1*y2)
+	yn := new(big.Int).Sub(y1y2, new(big.Int).Mul(a, x1x2))
+	yd := new(big.Int).Sub(big.NewInt(1), dxy)
+	yd.ModInverse(yd.Mod(yd, mod), mod)
+
+	x := xn.Mul(xn, xd)
+	y := yn.Mul(yn, yd)
+	return affine{x.Mod(x, mod), y.Mod(y, mod)}
+}
+
+func affineMul(p affine, k *big.Int) affine {
+	result := affine{big.NewInt(0), big.NewInt(1)}
+	for i := k.BitLen() - 1; i >= 0; i-- {
+		result = affineAdd(result, result)
+		if k.Bit(i) == 1 {
+			result = affineAdd(result, p)
+		}
+	}
+	return result
+}
+
+func (p affine) point() *Point {
+	return NewPoint(NewField(p.x), NewField(p.y))
+}
+
+func affineBase() affine {
+	return affine{Constants["gX"].BigInt(), Constants["gY"].BigInt()}
+}
+
+func randomScalar(r *rand.Rand) *big.Int {
+	return new(big.Int).Rand(r, curveFieldArith.modulus)
+}
+
+func randomAffine(r *rand.Rand) affine {
+	return affineMul(affineBase(), randomScalar(r))
+}
+
+func TestPointAddMatchesAffine(t *testing.T) {
+	r := rand.New(rand.NewSource(1))
+	for i := 0; i < 16; i++ {
+		p, q := randomAffine(r), randomAffine(r)
+		got := p.point().Add(q.point())
+		if want := affineAdd(p, q).point(); !got.Equal(want) || !got.IsOnCurve() {
+			t.Fatalf("%v + %v = %v, want %v", p.point(), q.point(), got, want)
+		}
+		// the unified formula also covers doubling and the identity
+		if got, want := p.point().Add(p.point()), affineAdd(p, p).point(); !got.Equal(want) {
+			t.Fatalf("%v + itself = %v, want %v", p.point(), got, want)
+		}
+		if got := p.point().Add(p.point().Neg()); !got.IsZero() {
+			t.Fatalf("%v - itself = %v, want the identity", p.point(), got)
+		}
+	}
+}
+
+func TestPointDoubleMatchesAffine(t *testing.T) {
+	r := rand.New(rand.NewSource(2))
+	for i := 0; i < 16; i++ {
+		p := randomAffine(r)
+		if got, want := p.point().Double(), affineAdd(p, p).point(); !got.Equal(want) {
+			t.Fatalf("2 * %v = %v, want %v", p.point(), got, want)
+		}
+	}
+}
+
+func TestPointMulMatchesAffine(t *testing.T) {
+	r := rand.New(rand.NewSource(3))
+	for i := 0; i < 8; i++ {
+		p := randomAffine(r)
+		k := randomScalar(r)
+		want := affineMul(p, k).point()
+		if got := p.point().Mul(NewField(k)); !got.Equal(want) {
+			t.Fatalf("%s * %v = %v, want %v", k, p.point(), got, want)
+		}
+		if got := p.point().MulSecret(NewField(k)); !got.Equal(want) {
+			t.Fatalf("MulSecret: %s * %v = %v, want %v", k, p.point(), got, want)
+		}
+	}
+	p := randomAffine(r)
+	if got := p.point().Mul(NewField(big.NewInt(0))); !got.IsZero() {
+		t.Fatalf("0 * %v = %v, want the identity", p.point(), got)
+	}
+	if got := p.point().Mul(NewField(curveFieldArith.modulus)); !got.IsZero() {
+		t.Fatalf("order * %v = %v, want the identity", p.point(), got)
+	}
+}
+
+func TestPointNegAndSubMatchAffine(t *testing.T) {
+	r := rand.New(rand.NewSource(6))
+	mod := fieldArith.modulus
+	identity := (&Point{}).Zero()
+	if !identity.Neg().IsZero() {
+		t.Fatalf("-identity = %v", identity.Neg())
+	}
+	for i := 0; i < 8; i++ {
+		p, q := randomAffine(r), randomAffine(r)
+		negQ := affine{new(big.Int).Mod(new(big.Int).Neg(q.x), mod), q.y}
+		if got := q.point().Neg(); !got.Equal(negQ.point()) || !got.Neg().Equal(q.point()) {
+			t.Fatalf("-%v = %v, want %v", q.point(), got, negQ.point())
+		}
+		if got, want := p.point().Sub(q.point()), affineAdd(p, negQ).point(); !got.Equal(want) {
+			t.Fatalf("%v - %v = %v, want %v", p.point(), q.point(), got, want)
+		}
+		if got := p.point().Sub(p.point()); !got.IsZero() {
+			t.Fatalf("%v - itself = %v, want the identity", p.point(), got)
+		}
+		if got := p.point().Sub(identity); !got.Equal(p.point()) {
+			t.Fatalf("%v - identity = %v", p.point(), got)
+		}
+	}
+}
+
+// naiveSum adds up scalars[i] * points[i] one Mul at a time
+func naiveSum(points []*Point, scalars []*Field) *Point {
+	sum := (&Point{}).Zero()
+	for i := range points {
+		sum = sum.Add(points[i].Mul(scalars[i]))
+	}
+	return sum
+}
+
+func TestMultiScalarMulMatchesNaiveSum(t *testing.T) {
+	r := rand.New(rand.NewSource(7))
+	zero := NewField(big.NewInt(0))
+	order := NewField(curveFieldArith.modulus)
+	var points []*Point
+	var scalars []*Field
+	for i := 0; i < 5; i++ {
+		points = append(points, randomAffine(r).point())
+		scalars = append(scalars, NewField(new(big.Int).Rand(r, fieldArith.modulus)))
+	}
+	cases := map[string]struct {
+		points  []*Point
+		scalars []*Field
+	}{
+		"empty":        {nil, nil},
+		"one":          {points[:1], scalars[:1]},
+		"several":      {points, scalars},
+		"zero scalars": {points[:3], []*Field{zero, zero, zero}},
+		"some zero":    {points[:3], []*Field{scalars[0], zero, scalars[2]}},
+		"repeated":     {[]*Point{points[0], points[0], points[0].Neg()}, scalars[:3]},
+		"identity":     {[]*Point{(&Point{}).Zero(), points[1]}, scalars[:2]},
+		"group order":  {points[:2], []*Field{order, scalars[1]}},
+		"small order":  {append(smallOrderPoints(t), points[0]), scalars},
+	}
+	for name, c := range cases {
+		got, err := MultiScalarMul(c.points, c.scalars)
+		if err != nil {
+			t.Fatalf("%s: %v", name, err)
+		}
+		if want := naiveSum(c.points, c.scalars); !got.Equal(want) {
+			t.Fatalf("%s: MultiScalarMul = %v, want %v", name, got, want)
+		}
+	}
+	if got, err := MultiScalarMul(points, scalars[:4]); !errors.Is(err, ErrLengthMismatch) || got != nil {
+		t.Fatalf("mismatched lengths = %v, %v, want ErrLengthMismatch", got, err)
+	}
+	if got, err := MultiScalarMul(nil, scalars[:1]); !errors.Is(err, ErrLengthMismatch) {
+		t.Fatalf("no points = %v, %v, want ErrLengthMismatch", got, err)
+	}
+}
+
+// yWithoutX returns a canonical y for which no x puts (x, y) on the curve
+func yWithoutX(t *testing.T) *Field {
+	t.Helper()
+	one := NewField(big.NewInt(1))
+	for i := int64(2); i < 100; i++ {
+		y := NewField(big.NewInt(i))
+		y2 := y.Square()
+		if !one.Sub(y2).Div(Constants["a"].Sub(Constants["d"].Mul(y2))).IsSquare() {
+			return y
+		}
+	}
+	t.Fatal("no y without an x below 100")
+	return nil
+}
+
+// There is no circomlib packPoint vector here: circomlib packs points of
+// Baby Jubjub in its a = 168700 form, not the a = -1 curve used here.
+func TestCompressRoundTrip(t *testing.T) {
+	r := rand.New(rand.NewSource(8))
+	points := append([]*Point{(&Point{}).Zero(), PointBase()}, smallOrderPoints(t)...)
+	for i := 0; i < 16; i++ {
+		points = append(points, randomAffine(r).point())
+	}
+	for _, p := range points {
+		b := p.Compress()
+		if negative := b[31]&0x80 != 0; negative != fieldIsNegative(p.x) {
+			t.Fatalf("sign bit of %v = %v", p, negative)
+		}
+		y := b
+		y[31] &= 0x7f
+		if got, err := FieldFromBytesLE(y[:]); err != nil || !got.Equal(p.y) {
+			t.Fatalf("low bits of %v = %v, %v, want y", p, got, err)
+		}
+		got, err := DecompressPoint(b[:])
+		if err != nil || !got.Equal(p) {
+			t.Fatalf("round trip of %v = %v, %v", p, got, err)
+		}
+	}
+}
+
+func TestDecompressRejectsInvalidEncodings(t *testing.T) {
+	valid := PointBase().Compress()
+	noX := yWithoutX(t).BytesLE()
+	// y = p with the sign bit clear and set
+	var yIsP [32]byte
+	fieldArith.modulus.FillBytes(yIsP[:])
+	for i, j := 0, 31; i < j; i, j = i+1, j-1 {
+		yIsP[i], yIsP[j] = yIsP[j], yIsP[i]
+	}
+	yIsPNegative := yIsP
+	yIsPNegative[31] |= 0x80
+	// the identity has x = 0, which has no negative form
+	negativeZero := (&Point{}).Zero().Compress()
+	negativeZero[31] |= 0x80
+	noXNegative := noX
+	noXNegative[31] |= 0x80
+
+	cases := map[string][]byte{
+		"short":                valid[:31],
+		"long":                 append(valid[:], 0),
+		"no x for y":           noX[:],
+		"no x for y, negative": noXNegative[:],
+		"y = p":                yIsP[:],
+		"y = p, negative":      yIsPNegative[:],
+		"negative zero x":      negativeZero[:],
+	}
+	for name, b := range cases {
+		if p, err := DecompressPoint(b); !errors.Is(err, ErrInvalidPoint) {
+			t.Fatalf("%s: decoded %v, %v", name, p, err)
+		}
+	}
+}
+
+func BenchmarkMul(b *testing.B) {
+	r := rand.New(rand.NewSource(4))
+	p := randomAffine(r).point()
+	k := NewField(randomScalar(r))
+	b.ReportAllocs()
+	b.ResetTimer()
+	for i := 0; i < b.N; i++ {
+		p.Mul(k)
+	}
+}
+
+func TestPointBaseMulMatchesAffine(t *testing.T) {
+	r := rand.New(rand.NewSource(5))
+	for i := 0; i < 8; i++ {
+		k := randomScalar(r)
+		if got, want := PointBaseMul(NewField(k)), affineMul(affineBase(), k).point(); !got.Equal(want) {
+			t.Fatalf("%s * G = %v, want %v", k, got, want)
+		}
+	}
+}
+
+// BenchmarkPointBaseMul uses the precomputed base table; the two
+// benchmarks after it measure the double-and-add paths it replaced
+func BenchmarkPointBaseMul(b *testing.B) {
+	k := NewField(randomScalar(rand.New(rand.NewSource(6))))
+	PointBaseMul(k)
+	b.ReportAllocs()
+	b.ResetTimer()
+	for i := 0; i < b.N; i++ {
+		PointBaseMul(k)
+	}
+}
+
+func BenchmarkPointBaseDoubleAndAdd(b *testing.B) {
+	k := NewField(randomScalar(rand.New(rand.NewSource(6))))
+	base := PointBase()
+	b.ReportAllocs()
+	b.ResetTimer()
+	for i := 0; i < b.N; i++ {
+		base.Mul(k)
+	}
+}
+
+func BenchmarkPointBaseAffineDoubleAndAdd(b *testing.B) {
+	k := randomScalar(rand.New(rand.NewSource(6)))
+	base := affineBase()
+	b.ReportAllocs()
+	b.ResetTimer()
+	for i := 0; i < b.N; i++ {
+		affineMul(base, k)
+	}
+}
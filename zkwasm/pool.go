@@ -0,0 +1,437 @@
+package zkwasm
+
+import (
+	"context"
+	"errors"
+	"math/big"
+	"sync"
+	"time"
+)
+
+var ErrNoHealthyEndpoint = errors.New("NoHealthyEndpoint")
+
+// RoutingStrategy selects the endpoint serving a query
+type RoutingStrategy int
+
+const (
+	// RoundRobin rotates queries over the healthy endpoints
+	RoundRobin RoutingStrategy = iota
+	// LowestLatency sends queries to the healthy endpoint with the lowest
+	// observed latency
+	LowestLatency
+)
+
+// PoolOption configures a ZKWasmPool
+type PoolOption func(*ZKWasmPool)
+
+// WithStrategy sets how queries are routed, RoundRobin by default
+func WithStrategy(strategy RoutingStrategy) PoolOption {
+	return func(p *ZKWasmPool) {
+		p.strategy = strategy
+	}
+}
+
+// WithEndpointOptions applies opts to the client of every endpoint
+func WithEndpointOptions(opts ...Option) PoolOption {
+	return func(p *ZKWasmPool) {
+		p.rpcOptions = append(p.rpcOptions, opts...)
+	}
+}
+
+// WithHealthCheck sets the interval of background health checks started by
+// Start, 10s by default
+func WithHealthCheck(interval time.Duration) PoolOption {
+	return func(p *ZKWasmPool) {
+		p.healthInterval = interval
+	}
+}
+
+// WithFailureThreshold sets how many consecutive failures mark an endpoint
+// unhealthy, 3 by default
+func WithFailureThreshold(failures int) PoolOption {
+	return func(p *ZKWasmPool) {
+		p.failThreshold = failures
+	}
+}
+
+type poolEndpoint struct {
+	url      string
+	rpc      *ZKWasmAppRpc
+	healthy  bool
+	failures int
+	latency  time.Duration
+	checked  time.Time
+}
+
+// EndpointStatus is a snapshot of the health of a pool endpoint
+type EndpointStatus struct {
+	URL       string
+	Healthy   bool
+	Failures  int
+	Latency   time.Duration
+	LastCheck time.Time
+}
+
+// ZKWasmPool spreads requests over several rollup endpoints serving the
+// same application. Queries are routed by the pool strategy; transactions
+// of a key stay pinned to one endpoint so nonces are read and used
+// consistently, and are moved only when that endpoint becomes unhealthy.
+type ZKWasmPool struct {
+	mu             sync.Mutex
+	endpoints      []*poolEndpoint
+	pins           map[string]*poolEndpoint
+	next           int
+	strategy       RoutingStrategy
+	rpcOptions     []Option
+	healthInterval time.Duration
+	failThreshold  int
+	stop           chan struct{}
+	done           chan struct{}
+}
+
+// NewZKWasmPool creates a pool over the endpoint base URLs. All endpoints
+// start healthy until a check or request says otherwise.
+func NewZKWasmPool(endpoints []string, opts ...PoolOption) *ZKWasmPool {
+	p := &ZKWasmPool{
+		pins:           make(map[string]*poolEndpoint),
+		healthInterval: 10 * time.Second,
+		failThreshold:  3,
+	}
+	for _, opt := range opts {
+		opt(p)
+	}
+	for _, url := range endpoints {
+		ep := &poolEndpoint{url: url, healthy: true}
+		rpcOptions := append(append([]Option{}, p.rpcOptions...), WithInterceptors(p.timer(ep)))
+		ep.rpc = NewZKWasmAppRpc(url, rpcOptions...)
+		p.endpoints = append(p.endpoints, ep)
+	}
+	return p
+}
+
+// timer folds the round trip of every successful request to ep into its
+// latency, so a transaction counts as its /send and job polls rather than
+// as a whole including the waits between polls
+func (p *ZKWasmPool) timer(ep *poolEndpoint) Interceptor {
+	return func(ctx context.Context, call *Call, next Handler) (map[string]interface{}, error) {
+		start := time.Now()
+		resp, err := next(ctx, call)
+		if err == nil {
+			p.mu.Lock()
+			p.observeLatency(ep, time.Since(start))
+			p.mu.Unlock()
+		}
+		return resp, err
+	}
+}
+
+// CheckHealth probes every endpoint through /config and records its
+// latency and health
+func (p *ZKWasmPool) CheckHealth(ctx context.Context) {
+	var wg sync.WaitGroup
+	for _, ep := range p.endpoints {
+		wg.Add(1)
+		go func(ep *poolEndpoint) {
+			defer wg.Done()
+			_, err := ep.rpc.QueryConfigContext(ctx)
+			p.mu.Lock()
+			defer p.mu.Unlock()
+			ep.checked = time.Now()
+			if err != nil {
+				ep.failures = p.failThreshold
+				ep.healthy = false
+				return
+			}
+			ep.failures = 0
+			ep.healthy = true
+		}(ep)
+	}
+	wg.Wait()
+}
+
+// Start runs CheckHealth in the background until Close is called
+func (p *ZKWasmPool) Start() {
+	p.mu.Lock()
+	if p.stop != nil {
+		p.mu.Unlock()
+		return
+	}
+	p.stop = make(chan struct{})
+	p.done = make(chan struct{})
+	stop, done := p.stop, p.done
+	p.mu.Unlock()
+
+	go func() {
+		defer close(done)
+		ticker := time.NewTicker(p.healthInterval)
+		defer ticker.Stop()
+		for {
+			ctx, cancel := context.WithTimeout(context.Background(), p.healthInterval)
+			p.CheckHealth(ctx)
+			cancel()
+			select {
+			case <-stop:
+				return
+			case <-ticker.C:
+			}
+		}
+	}()
+}
+
+// Close stops the background health checks
+func (p *ZKWasmPool) Close() {
+	p.mu.Lock()
+	stop, done := p.stop, p.done
+	p.stop, p.done = nil, nil
+	p.mu.Unlock()
+	if stop != nil {
+		close(stop)
+		<-done
+	}
+}
+
+// Status returns the health of every endpoint
+func (p *ZKWasmPool) Status() []EndpointStatus {
+	p.mu.Lock()
+	defer p.mu.Unlock()
+	var status []EndpointStatus
+	for _, ep := range p.endpoints {
+		status = append(status, EndpointStatus{
+			URL:       ep.url,
+			Healthy:   ep.healthy,
+			Failures:  ep.failures,
+			Latency:   ep.latency,
+			LastCheck: ep.checked,
+		})
+	}
+	return status
+}
+
+// observeLatency folds a sample into the moving average of ep
+func (p *ZKWasmPool) observeLatency(ep *poolEndpoint, sample time.Duration) {
+	if ep.latency == 0 {
+		ep.latency = sample
+		return
+	}
+	ep.latency = (ep.latency*7 + sample*3) / 10
+}
+
+// record updates endpoint health after a request. Only transport and
+// server failures count against an endpoint.
+func (p *ZKWasmPool) record(ep *poolEndpoint, err error) {
+	p.mu.Lock()
+	defer p.mu.Unlock()
+	if err == nil {
+		ep.failures = 0
+		ep.healthy = true
+		return
+	}
+	if kind := ClassifyError(err); kind == FailureNetwork || kind == FailureServer {
+		ep.failures++
+		if ep.failures >= p.failThreshold {
+			ep.healthy = false
+		}
+	}
+}
+
+func endpointFailover(err error) bool {
+	kind := ClassifyError(err)
+	return kind == FailureNetwork || kind == FailureServer
+}
+
+// candidates returns the healthy endpoints in routing order, or every
+// endpoint when none is healthy so requests can still probe for recovery
+func (p *ZKWasmPool) candidates() []*poolEndpoint {
+	p.mu.Lock()
+	defer p.mu.Unlock()
+	var healthy []*poolEndpoint
+	for _, ep := range p.endpoints {
+		if ep.healthy {
+			healthy = append(healthy, ep)
+		}
+	}
+	if len(healthy) == 0 {
+		healthy = append(healthy, p.endpoints...)
+	}
+	if len(healthy) == 0 {
+		return nil
+	}
+	switch p.strategy {
+	case LowestLatency:
+		best := 0
+		for i, ep := range healthy {
+			if ep.latency < healthy[best].latency {
+				best = i
+			}
+		}
+		healthy[0], healthy[best] = healthy[best], healthy[0]
+	default:
+		start := p.next % len(healthy)
+		p.next++
+		healthy = append(healthy[start:], healthy[:start]...)
+	}
+	return healthy
+}
+
+// query runs fn on the routed endpoints, failing over on transport and
+// server errors
+func (p *ZKWasmPool) query(fn func(rpc *ZKWasmAppRpc) error) error {
+	candidates := p.candidates()
+	if len(candidates) == 0 {
+		return ErrNoHealthyEndpoint
+	}
+	var err error
+	for _, ep := range candidates {
+		err = fn(ep.rpc)
+		p.record(ep, err)
+		if err == nil || !endpointFailover(err) {
+			return err
+		}
+	}
+	return err
+}
+
+// pinned returns the endpoint transactions of pkx are sent to, skipping
+// the endpoints already tried by the calling request. A key without a pin,
+// or whose endpoint is unhealthy or tried, is pinned to the first untried
+// candidate, falling back to untried unhealthy endpoints.
+func (p *ZKWasmPool) pinned(pkx string, tried map[*poolEndpoint]bool) (*poolEndpoint, error) {
+	p.mu.Lock()
+	ep, ok := p.pins[pkx]
+	usable := ok && ep.healthy && !tried[ep]
+	p.mu.Unlock()
+	if usable {
+		return ep, nil
+	}
+	for _, candidate := range append(p.candidates(), p.endpoints...) {
+		if tried[candidate] {
+			continue
+		}
+		p.mu.Lock()
+		p.pins[pkx] = candidate
+		p.mu.Unlock()
+		return candidate, nil
+	}
+	return nil, ErrNoHealthyEndpoint
+}
+
+func (p *ZKWasmPool) unpin(pkx string, ep *poolEndpoint) {
+	p.mu.Lock()
+	defer p.mu.Unlock()
+	if p.pins[pkx] == ep {
+		delete(p.pins, pkx)
+	}
+}
+
+// QueryStatePkx queries a player state on a routed endpoint
+func (p *ZKWasmPool) QueryStatePkx(ctx context.Context, pkx string) (map[string]interface{}, error) {
+	var state map[string]interface{}
+	err := p.query(func(rpc *ZKWasmAppRpc) error {
+		var err error
+		state, err = rpc.QueryStatePkx(ctx, pkx)
+		return err
+	})
+	return state, err
+}
+
+// QueryState queries the state of the player owning prikey
+func (p *ZKWasmPool) QueryState(prikey string) (map[string]interface{}, error) {
+	return p.QueryStateContext(context.Background(), prikey)
+}
+
+// QueryStateContext is QueryState with a context bounding the request
+func (p *ZKWasmPool) QueryStateContext(ctx context.Context, prikey string) (map[string]interface{}, error) {
+	return p.QueryStatePkx(ctx, Query(prikey)["pkx"])
+}
+
+// QueryConfig queries the application config on a routed endpoint
+func (p *ZKWasmPool) QueryConfig() (map[string]interface{}, error) {
+	return p.QueryConfigContext(context.Background())
+}
+
+// QueryConfigContext is QueryConfig with a context bounding the request
+func (p *ZKWasmPool) QueryConfigContext(ctx context.Context) (map[string]interface{}, error) {
+	var config map[string]interface{}
+	err := p.query(func(rpc *ZKWasmAppRpc) error {
+		var err error
+		config, err = rpc.QueryConfigContext(ctx)
+		return err
+	})
+	return config, err
+}
+
+// GetNoncePkx reads the nonce of the player identified by pkx from the
+// endpoint its transactions are pinned to, failing over to each other
+// endpoint at most once
+func (p *ZKWasmPool) GetNoncePkx(ctx context.Context, pkx string) (*big.Int, error) {
+	var lastErr error = ErrNoHealthyEndpoint
+	tried := make(map[*poolEndpoint]bool)
+	for {
+		ep, err := p.pinned(pkx, tried)
+		if err != nil {
+			return nil, lastErr
+		}
+		tried[ep] = true
+		nonce, err := ep.rpc.GetNoncePkx(ctx, pkx)
+		p.record(ep, err)
+		if err == nil || !endpointFailover(err) {
+			return nonce, err
+		}
+		lastErr = err
+		p.unpin(pkx, ep)
+	}
+}
+
+// GetNonce returns the nonce of the player owning prikey
+func (p *ZKWasmPool) GetNonce(prikey string) (*big.Int, error) {
+	return p.GetNonceContext(context.Background(), prikey)
+}
+
+// GetNonceContext is GetNonce with a context bounding the state query
+func (p *ZKWasmPool) GetNonceContext(ctx context.Context, prikey string) (*big.Int, error) {
+	return p.GetNoncePkx(ctx, Query(prikey)["pkx"])
+}
+
+// SendTransactionWithSigner sends a transaction through the endpoint the
+// signer is pinned to. When the send itself fails at the transport or
+// server level the key is re-pinned and the transaction sent to the next
+// endpoint not tried yet; the nonce keeps a duplicate from applying twice.
+func (p *ZKWasmPool) SendTransactionWithSigner(ctx context.Context, signer Signer, cmd [4]*big.Int) (*TransactionResult, error) {
+	pkx := signer.Pkx()
+	var lastErr error = ErrNoHealthyEndpoint
+	tried := make(map[*poolEndpoint]bool)
+	for {
+		ep, err := p.pinned(pkx, tried)
+		if err != nil {
+			return nil, lastErr
+		}
+		tried[ep] = true
+		result, err := ep.rpc.SendTransactionWithSigner(ctx, signer, cmd)
+		p.record(ep, err)
+		if err == nil || !endpointFailover(err) {
+			return result, err
+		}
+		lastErr = err
+		p.unpin(pkx, ep)
+	}
+}
+
+// SendTransaction signs cmd with prikey and sends it like
+// SendTransactionWithSigner
+func (p *ZKWasmPool) SendTransaction(cmd [4]*big.Int, prikey string) (string, error) {
+	result, err := p.SendTransactionResult(cmd, prikey)
+	if err != nil {
+		return "", err
+	}
+	return result.ReturnValue, nil
+}
+
+// SendTransactionResult is SendTransaction also reporting the job id
+func (p *ZKWasmPool) SendTransactionResult(cmd [4]*big.Int, prikey string) (*TransactionResult, error) {
+	return p.SendTransactionResultContext(context.Background(), cmd, prikey)
+}
+
+// SendTransactionResultContext is SendTransactionResult with a context
+func (p *ZKWasmPool) SendTransactionResultContext(ctx context.Context, cmd [4]*big.Int, prikey string) (*TransactionResult, error) {
+	return p.SendTransactionWithSigner(ctx, NewKeySigner(prikey), cmd)
+}
@@ -0,0 +1,111 @@
+package zkwasm
+
+import (
+	"context"
+	"net/http"
+	"net/http/httptest"
+	"sync/atomic"
+	"testing"
+	"time"
+)
+
+func TestPoolLatencyExcludesJobPolling(t *testing.T) {
+	fake, server := newFakeRollup(t)
+	pool := NewZKWasmPool([]string{server.URL})
+	start := time.Now()
+	if _, err := pool.SendTransactionWithSigner(context.Background(), NewKeySigner("1234"), testCommand(0)); err != nil {
+		t.Fatal(err)
+	}
+	elapsed := time.Since(start)
+	latency := pool.Status()[0].Latency
+	if latency <= 0 || latency > elapsed/4 {
+		t.Fatalf("latency %s for a transaction of %s; want the request round trips only", latency, elapsed)
+	}
+	if fake.appliedCount() != 1 {
+		t.Fatalf("applied %d times", fake.appliedCount())
+	}
+}
+
+func TestPoolFailsOver(t *testing.T) {
+	down := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		http.Error(w, "unavailable", http.StatusServiceUnavailable)
+	}))
+	defer down.Close()
+	_, up := newFakeRollup(t)
+	pool := NewZKWasmPool([]string{down.URL, up.URL}, WithFailureThreshold(1))
+
+	var client AppClient = pool
+	nonce, err := client.GetNonce("1234")
+	if err != nil || nonce.Sign() != 0 {
+		t.Fatalf("nonce = %v, err = %v", nonce, err)
+	}
+	if _, err := client.QueryConfig(); err != nil {
+		// the fake serves no /config; a 404 must not mark it unhealthy
+		if kind := ClassifyError(err); kind != FailureApplication {
+			t.Fatalf("err = %v", err)
+		}
+	}
+	status := pool.Status()
+	if status[0].Healthy || !status[1].Healthy {
+		t.Fatalf("status = %+v, want only the second endpoint healthy", status)
+	}
+}
+
+func TestPoolFailoverTriesEachEndpointOnce(t *testing.T) {
+	var downHits [2]atomic.Int32
+	var urls []string
+	for i := range downHits {
+		hits := &downHits[i]
+		down := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+			hits.Add(1)
+			http.Error(w, "unavailable", http.StatusServiceUnavailable)
+		}))
+		defer down.Close()
+		urls = append(urls, down.URL)
+	}
+	fake, up := newFakeRollup(t)
+	// the default threshold keeps a failing endpoint healthy after one
+	// failure, so only the tried set stops it being picked again
+	pool := NewZKWasmPool(append(urls, up.URL))
+	ctx := context.Background()
+	signer := NewKeySigner("1234")
+
+	nonce, err := pool.GetNoncePkx(ctx, signer.Pkx())
+	if err != nil || nonce.Sign() != 0 {
+		t.Fatalf("nonce = %v, err = %v", nonce, err)
+	}
+	for i := range downHits {
+		if hits := downHits[i].Swap(0); hits > 1 {
+			t.Fatalf("GetNoncePkx hit failing endpoint %d %d times, want at most once", i, hits)
+		}
+	}
+
+	// re-pin the key to a failing endpoint before sending
+	pool.mu.Lock()
+	pool.pins[signer.Pkx()] = pool.endpoints[0]
+	pool.mu.Unlock()
+	if _, err := pool.SendTransactionWithSigner(ctx, signer, testCommand(0)); err != nil {
+		t.Fatal(err)
+	}
+	for i := range downHits {
+		if hits := downHits[i].Load(); hits > 1 {
+			t.Fatalf("send hit failing endpoint %d %d times, want at most once", i, hits)
+		}
+	}
+	if downHits[0].Load() != 1 || fake.appliedCount() != 1 {
+		t.Fatalf("first endpoint hit %d times, applied %d; want the pinned endpoint tried then the send applied once", downHits[0].Load(), fake.appliedCount())
+	}
+
+	// with every endpoint failing each is tried once and the call ends
+	// with the last failure
+	up.Close()
+	downHits[0].Store(0)
+	downHits[1].Store(0)
+	_, err = pool.GetNoncePkx(ctx, signer.Pkx())
+	if kind := ClassifyError(err); kind != FailureNetwork && kind != FailureServer {
+		t.Fatalf("err = %v, want the last endpoint failure", err)
+	}
+	if downHits[0].Load() != 1 || downHits[1].Load() != 1 {
+		t.Fatalf("failing endpoints hit %d and %d times, want once each", downHits[0].Load(), downHits[1].Load())
+	}
+}
@@ -0,0 +1,221 @@
+package zkwasm
+
+import (
+	"math/big"
+	"sync"
+)
+
+// Poseidon parameters of the zkWasm host, Poseidon<Fr, 9, 8>::new(8, 63)
+// from the PSE poseidon crate over the BN254 scalar field
+const (
+	PoseidonWidth         = 9
+	PoseidonRate          = PoseidonWidth - 1
+	PoseidonFullRounds    = 8
+	PoseidonPartialRounds = 63
+)
+
+var (
+	poseidonOnce      sync.Once
+	poseidonConstants [][PoseidonWidth]*Field
+	poseidonMDS       [PoseidonWidth][PoseidonWidth]*Field
+)
+
+// Poseidon is the sponge used by the zkWasm host: the capacity element
+// starts at 2^64, inputs are added to the rate elements eight at a time,
+// and squeezing pads the pending inputs with a one and returns state[1]
+// after a final permutation.
+type Poseidon struct {
+	state     [PoseidonWidth]*Field
+	absorbing []*Field
+}
+
+// NewPoseidon creates an empty sponge
+func NewPoseidon() *Poseidon {
+	poseidonOnce.Do(generatePoseidonParams)
+	h := &Poseidon{}
+	for i := range h.state {
+		h.state[i] = NewField(big.NewInt(0))
+	}
+	h.state[0] = NewField(new(big.Int).Lsh(big.NewInt(1), 64))
+	return h
+}
+
+// Update absorbs elements
+func (h *Poseidon) Update(elements ...*Field) {
+	h.absorbing = append(h.absorbing, elements...)
+	for len(h.absorbing) >= PoseidonRate {
+		h.absorb(h.absorbing[:PoseidonRate])
+		h.absorbing = h.absorbing[PoseidonRate:]
+	}
+}
+
+// Squeeze finishes the absorbed input and returns a copy of the hash. The
+// sponge stays usable; further updates continue from the squeezed state.
+func (h *Poseidon) Squeeze() *Field {
+	last := append(append([]*Field{}, h.absorbing...), NewField(big.NewInt(1)))
+	h.absorbing = nil
+	h.absorb(last)
+	hash := *h.state[1]
+	return &hash
+}
+
+func (h *Poseidon) absorb(chunk []*Field) {
+	for i, element := range chunk {
+		h.state[i+1] = h.state[i+1].Add(element)
+	}
+	poseidonPermute(&h.state)
+}
+
+// PoseidonHash hashes elements with a fresh sponge
+func PoseidonHash(elements ...*Field) *Field {
+	h := NewPoseidon()
+	h.Update(elements...)
+	return h.Squeeze()
+}
+
+// PoseidonHashU64 hashes u64 values the way the zkWasm poseidon host
+// functions do: the values are zero-padded to a multiple of four, each
+// group of four little-endian limbs is one field element, and the hash is
+// returned as four little-endian limbs
+func PoseidonHashU64(values []uint64) [4]uint64 {
+	var elements []*Field
+	for i := 0; i < len(values); i += 4 {
+		var group limbs
+		copy(group[:], values[i:min(i+4, len(values))])
+		buf := group.bytes()
+		elements = append(elements, NewField(new(big.Int).SetBytes(buf[:])))
+	}
+	hash := PoseidonHash(elements...)
+	return fieldArith.reduce(&hash.m)
+}
+
+func poseidonPermute(state *[PoseidonWidth]*Field) {
+	half := PoseidonFullRounds / 2
+	for round, constants := range poseidonConstants {
+		for i := range state {
+			state[i] = state[i].Add(constants[i])
+		}
+		if round < half || round >= half+PoseidonPartialRounds {
+			for i := range state {
+				state[i] = poseidonSbox(state[i])
+			}
+		} else {
+			state[0] = poseidonSbox(state[0])
+		}
+		var mixed [PoseidonWidth]*Field
+		for i := range mixed {
+			sum := NewField(big.NewInt(0))
+			for j := range state {
+				sum = sum.Add(poseidonMDS[i][j].Mul(state[j]))
+			}
+			mixed[i] = sum
+		}
+		*state = mixed
+	}
+}
+
+// poseidonSbox is x^5
+func poseidonSbox(x *Field) *Field {
+	x2 := x.Square()
+	return x2.Square().Mul(x)
+}
+
+// grain is the LFSR of the Poseidon reference implementation deriving
+// round constants and the MDS matrix
+type grain struct {
+	state [80]bool
+}
+
+func newGrain(fieldBits, width, fullRounds, partialRounds int) *grain {
+	var bits []bool
+	appendBits := func(n int, v int) {
+		for i := n - 1; i >= 0; i-- {
+			bits = append(bits, (v>>uint(i))&1 == 1)
+		}
+	}
+	appendBits(2, 1) // prime field
+	appendBits(4, 0) // x^alpha s-box
+	appendBits(12, fieldBits)
+	appendBits(12, width)
+	appendBits(10, fullRounds)
+	appendBits(10, partialRounds)
+	appendBits(30, 1<<30-1)
+	g := &grain{}
+	copy(g.state[:], bits)
+	for i := 0; i < 160; i++ {
+		g.newBit()
+	}
+	return g
+}
+
+func (g *grain) newBit() bool {
+	bit := g.state[62] != g.state[51] != g.state[38] != g.state[23] != g.state[13] != g.state[0]
+	copy(g.state[:], g.state[1:])
+	g.state[79] = bit
+	return bit
+}
+
+// nextBit applies the self-shrinking step: pairs are drawn until the first
+// bit is set and the second bit is returned
+func (g *grain) nextBit() bool {
+	for !g.newBit() {
+		g.newBit()
+	}
+	return g.newBit()
+}
+
+// nextValue reads an MSB-first value of the field bit length
+func (g *grain) nextValue(bits int) *big.Int {
+	v := new(big.Int)
+	for i := 0; i < bits; i++ {
+		v.Lsh(v, 1)
+		if g.nextBit() {
+			v.SetBit(v, 0, 1)
+		}
+	}
+	return v
+}
+
+// nextFieldElement samples with rejection of values not below the modulus
+func (g *grain) nextFieldElement(bits int) *Field {
+	for {
+		if v := g.nextValue(bits); v.Cmp(fieldArith.modulus) < 0 {
+			return NewField(v)
+		}
+	}
+}
+
+func generatePoseidonParams() {
+	bits := fieldArith.modulus.BitLen()
+	g := newGrain(bits, PoseidonWidth, PoseidonFullRounds, PoseidonPartialRounds)
+	poseidonConstants = make([][PoseidonWidth]*Field, PoseidonFullRounds+PoseidonPartialRounds)
+	for r := range poseidonConstants {
+		for i := range poseidonConstants[r] {
+			poseidonConstants[r][i] = g.nextFieldElement(bits)
+		}
+	}
+
+	// Cauchy matrix 1/(x_i + y_j) over distinct x and y, sampled without
+	// rejection
+	for {
+		var xs, ys [PoseidonWidth]*Field
+		seen := make(map[string]bool)
+		for i := range xs {
+			xs[i] = NewField(g.nextValue(bits))
+			seen[xs[i].String()] = true
+		}
+		for i := range ys {
+			ys[i] = NewField(g.nextValue(bits))
+			seen[ys[i].String()] = true
+		}
+		if len(seen) != 2*PoseidonWidth {
+			continue
+		}
+		for i := range xs {
+			for j := range ys {
+				poseidonMDS[i][j] = xs[i].Add(ys[j]).Inv()
+			}
+		}
+		return
+	}
+}
@@ -1,13 +1,16 @@
 package zkwasm
 
 import (
+	"crypto/rand"
 	"crypto/sha256"
 	"fmt"
+	"io"
 	"math/big"
-	"math/rand"
-	"time"
 )
 
+// randReader is the source of private keys and signature nonces
+var randReader io.Reader = rand.Reader
+
 // PrivateKey represents a private key on the elliptic curve
 type PrivateKey struct {
 	key  *CurveField
@@ -19,15 +22,23 @@ func NewPrivateKey(key *CurveField) *PrivateKey {
 	return &PrivateKey{key: key}
 }
 
+// randomCurveScalar draws a uniform non-zero scalar below the curve order
+// from crypto/rand
+func randomCurveScalar() *CurveField {
+	for {
+		k, err := rand.Int(randReader, curveFieldArith.modulus)
+		if err != nil {
+			panic("random byte generation failed: " + err.Error())
+		}
+		if k.Sign() != 0 {
+			return NewCurveField(k)
+		}
+	}
+}
+
 // RandomPrivateKey generates a random private key
 func RandomPrivateKey() *PrivateKey {
-	rand.Seed(time.Now().UnixNano())
-	bytes := make([]byte, 32)
-	_, err := rand.Read(bytes)
-	if err != nil {
-		panic("random byte generation failed")
-	}
-	return NewPrivateKey(NewCurveField(new(big.Int).SetBytes(bytes)))
+	return NewPrivateKey(randomCurveScalar())
 }
 
 // PrivateKeyFromString creates a private key from a hex string
@@ -38,18 +49,13 @@ func PrivateKeyFromString(s string) *PrivateKey {
 
 // ToString converts the private key to a hexadecimal string
 func (pk *PrivateKey) ToString() string {
-	return fmt.Sprintf("%x", pk.key.v.Bytes())
+	return fmt.Sprintf("%x", pk.key.BigInt().Bytes())
 }
 
-// R generates a random scalar value r
+// R generates the random signature nonce r. Each call draws a fresh value
+// from crypto/rand, so no two signatures share a nonce.
 func (pk *PrivateKey) R() *CurveField {
-	rand.Seed(time.Now().UnixNano())
-	bytes := make([]byte, 32)
-	_, err := rand.Read(bytes)
-	if err != nil {
-		panic("random byte generation failed")
-	}
-	return NewCurveField(new(big.Int).SetBytes(bytes))
+	return randomCurveScalar()
 }
 
 // PublicKey returns the public key corresponding to this private key
@@ -64,14 +70,14 @@ func (pk *PrivateKey) PublicKey() *PublicKey {
 func (pk *PrivateKey) Sign(message []byte) [2][][]byte {
 	// Ax = public key's x coordinate
 	Ax := pk.PublicKey().key.x
-	r := pk.R()                       // Random value r
-	R := PointBase().Mul((*Field)(r)) // R = r * G
+	r := pk.R()                        // Random value r
+	R := PointBaseMulSecret(r.Field()) // R = r * G
 	Rx := R.x
 
 	// Create the content for hashing: Rx || Ax || message
 	var content []byte
-	content = append(content, Rx.v.Bytes()...)
-	content = append(content, Ax.v.Bytes()...)
+	content = append(content, Rx.BigInt().Bytes()...)
+	content = append(content, Ax.BigInt().Bytes()...)
 	content = append(content, message...)
 
 	// Hash the content using SHA-256
@@ -81,5 +87,5 @@ func (pk *PrivateKey) Sign(message []byte) [2][][]byte {
 	// Calculate S = r + H * privateKey
 	S := r.Add(pk.key.Mul(NewCurveField(H)))
 
-	return [2][][]byte{{Rx.v.Bytes(), Rx.v.Bytes()}, {S.v.Bytes()}}
+	return [2][][]byte{{Rx.BigInt().Bytes(), Rx.BigInt().Bytes()}, {S.BigInt().Bytes()}}
 }
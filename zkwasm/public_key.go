@@ -1,5 +1,11 @@
 package zkwasm
 
+import (
+	"encoding/hex"
+	"fmt"
+	"strings"
+)
+
 type PublicKey struct {
 	key *Point
 }
@@ -9,5 +15,97 @@ func NewPublicKey(key *Point) *PublicKey {
 }
 
 func PublicKeyFromPrivateKey(pk *PrivateKey) *PublicKey {
-	return NewPublicKey(PointBase().Mul((*Field)(pk.key)))
+	return NewPublicKey(PointBaseMulSecret(pk.key.Field()))
+}
+
+// PublicKeyFromBytes decodes a 32-byte compressed public key
+func PublicKeyFromBytes(b []byte) (*PublicKey, error) {
+	p, err := DecompressPoint(b)
+	if err != nil {
+		return nil, err
+	}
+	if p.IsZero() || !p.IsInSubgroup() {
+		return nil, fmt.Errorf("%w: not a public key", ErrInvalidPoint)
+	}
+	return NewPublicKey(p), nil
+}
+
+// PublicKeyFromHex decodes a hex compressed public key
+func PublicKeyFromHex(s string) (*PublicKey, error) {
+	b, err := hex.DecodeString(strings.TrimPrefix(s, "0x"))
+	if err != nil {
+		return nil, fmt.Errorf("%w: %v", ErrInvalidPoint, err)
+	}
+	return PublicKeyFromBytes(b)
+}
+
+// PublicKeyFromPkx recovers the public key from the little-endian hex x
+// coordinate sent to the rollup, picking the y that puts the key in the
+// prime-order subgroup
+func PublicKeyFromPkx(pkx string) (*PublicKey, error) {
+	hexStr := strings.TrimPrefix(pkx, "0x")
+	if len(hexStr) != 64 {
+		return nil, fmt.Errorf("%w: expected 64 hex characters, got %d", ErrInvalidPkx, len(hexStr))
+	}
+	b, err := hex.DecodeString(hexStr)
+	if err != nil {
+		return nil, fmt.Errorf("%w: %s is not hex", ErrInvalidPkx, pkx)
+	}
+	x, err := FieldFromBytesLE(b)
+	if err != nil {
+		return nil, fmt.Errorf("%w: %v", ErrInvalidPkx, err)
+	}
+	p, err := pointFromX(x)
+	if err != nil {
+		return nil, err
+	}
+	if p.IsZero() {
+		return nil, fmt.Errorf("%w: not a public key", ErrInvalidPoint)
+	}
+	return NewPublicKey(p), nil
+}
+
+// Pkx returns the little-endian hex x coordinate identifying the player
+func (pk *PublicKey) Pkx() string {
+	return BnToHexLe(pk.key.x.BigInt())
+}
+
+// Pky returns the little-endian hex y coordinate
+func (pk *PublicKey) Pky() string {
+	return BnToHexLe(pk.key.y.BigInt())
+}
+
+// Bytes returns the 32-byte compressed encoding
+func (pk *PublicKey) Bytes() []byte {
+	b := pk.key.Compress()
+	return b[:]
+}
+
+// Hex returns the compressed encoding in hex
+func (pk *PublicKey) Hex() string {
+	return hex.EncodeToString(pk.Bytes())
+}
+
+func (pk *PublicKey) String() string {
+	return pk.Hex()
+}
+
+// Equal reports whether pk and other are the same key
+func (pk *PublicKey) Equal(other *PublicKey) bool {
+	return pk.key.Equal(other.key)
+}
+
+// MarshalText encodes the key as compressed hex, which is also its JSON
+// form
+func (pk *PublicKey) MarshalText() ([]byte, error) {
+	return []byte(pk.Hex()), nil
+}
+
+func (pk *PublicKey) UnmarshalText(text []byte) error {
+	decoded, err := PublicKeyFromHex(string(text))
+	if err != nil {
+		return err
+	}
+	*pk = *decoded
+	return nil
 }
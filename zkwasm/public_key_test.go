@@ -0,0 +1,131 @@
+package zkwasm
+
+import (
+	"encoding/hex"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"math/big"
+	"strings"
+	"testing"
+)
+
+func testPublicKeys() []*PublicKey {
+	var keys []*PublicKey
+	for _, s := range []string{"1", "1234", "deadbeef", strings.Repeat("ab", 31)} {
+		keys = append(keys, PrivateKeyFromString(s).PublicKey())
+	}
+	return keys
+}
+
+func TestPublicKeyRoundTrips(t *testing.T) {
+	for _, pk := range testPublicKeys() {
+		if got, err := PublicKeyFromBytes(pk.Bytes()); err != nil || !got.Equal(pk) {
+			t.Fatalf("bytes round trip of %s = %v, %v", pk, got, err)
+		}
+		if got, err := PublicKeyFromHex("0x" + pk.Hex()); err != nil || !got.Equal(pk) {
+			t.Fatalf("hex round trip of %s = %v, %v", pk, got, err)
+		}
+		if got, err := PublicKeyFromPkx(pk.Pkx()); err != nil || !got.Equal(pk) || got.Pky() != pk.Pky() {
+			t.Fatalf("pkx round trip of %s = %v, %v", pk, got, err)
+		}
+
+		text, err := pk.MarshalText()
+		if err != nil || string(text) != pk.Hex() {
+			t.Fatalf("MarshalText of %s = %q, %v", pk, text, err)
+		}
+		var got PublicKey
+		if err := got.UnmarshalText(text); err != nil || !got.Equal(pk) {
+			t.Fatalf("text round trip of %s = %v, %v", pk, &got, err)
+		}
+		type wrapper struct {
+			Key *PublicKey `json:"key"`
+		}
+		data, err := json.Marshal(wrapper{Key: pk})
+		if err != nil {
+			t.Fatal(err)
+		}
+		var decoded wrapper
+		if err := json.Unmarshal(data, &decoded); err != nil || !decoded.Key.Equal(pk) {
+			t.Fatalf("JSON round trip of %s = %s, %v", pk, data, err)
+		}
+	}
+}
+
+func TestPublicKeyFromBytesRejectsInvalid(t *testing.T) {
+	valid := PointBase().Compress()
+	noX := yWithoutX(t).BytesLE()
+	highBit := (&Point{}).Zero().Compress()
+	highBit[31] |= 0x80
+	cases := map[string][]byte{
+		"short":           valid[:31],
+		"not a square":    noX[:],
+		"negative zero x": highBit[:],
+	}
+	// on the curve but the identity or outside the subgroup
+	for i, p := range smallOrderPoints(t) {
+		b := p.Compress()
+		cases[fmt.Sprintf("small order %d", i)] = b[:]
+	}
+	pk := testPublicKeys()[0]
+	mixed := pk.key.Add(smallOrderPoints(t)[1]).Compress()
+	cases["mixed order"] = mixed[:]
+
+	for name, b := range cases {
+		if got, err := PublicKeyFromBytes(b); !errors.Is(err, ErrInvalidPoint) {
+			t.Fatalf("%s: decoded %v, %v", name, got, err)
+		}
+	}
+	if got, err := PublicKeyFromHex("zz"); !errors.Is(err, ErrInvalidPoint) {
+		t.Fatalf("non-hex decoded %v, %v", got, err)
+	}
+}
+
+func TestPublicKeyFromPkxRejectsInvalid(t *testing.T) {
+	le := func(v *big.Int) string { return BnToHexLe(v) }
+	var noY *big.Int
+	one := NewField(big.NewInt(1))
+	for i := int64(2); noY == nil; i++ {
+		x := NewField(big.NewInt(i))
+		x2 := x.Square()
+		if !one.Sub(Constants["a"].Mul(x2)).Div(one.Sub(Constants["d"].Mul(x2))).IsSquare() {
+			noY = big.NewInt(i)
+		}
+	}
+	// the points of order 2 have no subgroup point sharing their x
+	order2 := smallOrderPoints(t)[2].x.BigInt()
+
+	for name, c := range map[string]struct {
+		pkx  string
+		want error
+	}{
+		"short":            {le(big.NewInt(1))[:62], ErrInvalidPkx},
+		"not hex":          {strings.Repeat("zz", 32), ErrInvalidPkx},
+		"x = p":            {le(fieldArith.modulus), ErrInvalidPkx},
+		"not on curve":     {le(noY), ErrInvalidPoint},
+		"identity":         {le(big.NewInt(0)), ErrInvalidPoint},
+		"outside subgroup": {le(order2), ErrInvalidPoint},
+	} {
+		if got, err := PublicKeyFromPkx(c.pkx); !errors.Is(err, c.want) {
+			t.Fatalf("%s: decoded %v, %v, want %v", name, got, err, c.want)
+		}
+	}
+}
+
+func TestUnmarshalTextRejectsInvalid(t *testing.T) {
+	pk := testPublicKeys()[1]
+	got := *pk
+	noX := yWithoutX(t).BytesLE()
+	for _, text := range []string{"", "zz", pk.Hex()[:62], hex.EncodeToString(noX[:])} {
+		if err := got.UnmarshalText([]byte(text)); !errors.Is(err, ErrInvalidPoint) {
+			t.Fatalf("UnmarshalText(%q) = %v", text, err)
+		}
+		if !got.Equal(pk) {
+			t.Fatalf("UnmarshalText(%q) changed the key to %v", text, &got)
+		}
+	}
+	var decoded struct{ Key *PublicKey }
+	if err := json.Unmarshal([]byte(`{"Key":"00"}`), &decoded); !errors.Is(err, ErrInvalidPoint) {
+		t.Fatalf("JSON with a bad key = %v", err)
+	}
+}
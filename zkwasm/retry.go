@@ -0,0 +1,256 @@
+package zkwasm
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"math/big"
+	"net"
+	"net/http"
+	"net/url"
+	"strings"
+	"time"
+)
+
+// FailureKind classifies why a transaction attempt failed
+type FailureKind int
+
+const (
+	FailureUnknown FailureKind = iota
+	// FailureNetwork is a transport error talking to the server
+	FailureNetwork
+	// FailureServer is a 5xx response
+	FailureServer
+	// FailureRateLimited is a 429 response
+	FailureRateLimited
+	// FailureNonce is a rejection caused by a stale or reused nonce
+	FailureNonce
+	// FailureApplication is a job failedReason or other rejection by the
+	// application
+	FailureApplication
+	// FailureTimeout means the job did not finish while being monitored;
+	// it may still land
+	FailureTimeout
+	// FailureCanceled means the context ended
+	FailureCanceled
+	// FailureOutcomeUnknown means an attempt may have landed but the
+	// transaction can no longer be retried safely
+	FailureOutcomeUnknown
+)
+
+// ErrOutcomeUnknown is returned when a retry hits a nonce conflict after
+// an attempt whose outcome is unknown. That attempt may have been applied,
+// so signing the command again with a fresh nonce could apply it twice.
+var ErrOutcomeUnknown = errors.New("TransactionOutcomeUnknown")
+
+func (k FailureKind) String() string {
+	switch k {
+	case FailureNetwork:
+		return "network"
+	case FailureServer:
+		return "server"
+	case FailureRateLimited:
+		return "rate-limited"
+	case FailureNonce:
+		return "nonce"
+	case FailureApplication:
+		return "application"
+	case FailureTimeout:
+		return "timeout"
+	case FailureCanceled:
+		return "canceled"
+	case FailureOutcomeUnknown:
+		return "outcome-unknown"
+	}
+	return "unknown"
+}
+
+// ClassifyError returns the FailureKind of an error returned by the client
+func ClassifyError(err error) FailureKind {
+	var jobErr *JobFailedError
+	var statusErr *StatusError
+	var netErr net.Error
+	var urlErr *url.Error
+	switch {
+	case err == nil:
+		return FailureUnknown
+	case errors.Is(err, context.Canceled), errors.Is(err, context.DeadlineExceeded):
+		return FailureCanceled
+	case errors.Is(err, ErrOutcomeUnknown):
+		return FailureOutcomeUnknown
+	case errors.Is(err, ErrMonitorTransactionFail):
+		return FailureTimeout
+	case errors.As(err, &jobErr):
+		if isNonceMessage(jobErr.Reason) {
+			return FailureNonce
+		}
+		return FailureApplication
+	case errors.As(err, &statusErr):
+		switch {
+		case statusErr.StatusCode == http.StatusTooManyRequests:
+			return FailureRateLimited
+		case statusErr.StatusCode >= 500:
+			if isNonceMessage(statusErr.Body) {
+				return FailureNonce
+			}
+			return FailureServer
+		case isNonceMessage(statusErr.Body):
+			return FailureNonce
+		}
+		return FailureApplication
+	case errors.As(err, &netErr), errors.As(err, &urlErr):
+		return FailureNetwork
+	}
+	return FailureUnknown
+}
+
+func isNonceMessage(message string) bool {
+	return strings.Contains(strings.ToLower(message), "nonce")
+}
+
+// RetryPolicy controls how SendTransaction retries failed attempts.
+// Network errors, 5xx and 429 responses resend the same command: the
+// rollup accepts a nonce once, so a duplicate cannot apply twice. Nonce
+// conflicts refresh the nonce with GetNonce and re-sign, but only while
+// every earlier attempt was rejected outright; after a network error or
+// 5xx the first send may have landed, and the conflict ends the retries
+// with ErrOutcomeUnknown instead. Application failures and monitoring
+// timeouts are never retried.
+type RetryPolicy struct {
+	// MaxAttempts bounds the number of sends, including the first
+	MaxAttempts int
+	// InitialBackoff is the delay before the second attempt
+	InitialBackoff time.Duration
+	// MaxBackoff caps the delay between attempts
+	MaxBackoff time.Duration
+	// Multiplier grows the delay after each attempt
+	Multiplier float64
+}
+
+// DefaultRetryPolicy returns a policy of 4 attempts backing off from
+// 500ms up to 8s
+func DefaultRetryPolicy() RetryPolicy {
+	return RetryPolicy{
+		MaxAttempts:    4,
+		InitialBackoff: 500 * time.Millisecond,
+		MaxBackoff:     8 * time.Second,
+		Multiplier:     2,
+	}
+}
+
+// WithRetry enables retries of SendTransaction according to policy
+func WithRetry(policy RetryPolicy) Option {
+	return func(rpc *ZKWasmAppRpc) {
+		rpc.retry = &policy
+	}
+}
+
+func (p *RetryPolicy) backoff(attempt int) time.Duration {
+	d := float64(p.InitialBackoff)
+	for i := 1; i < attempt; i++ {
+		d *= p.Multiplier
+	}
+	if p.MaxBackoff > 0 && d > float64(p.MaxBackoff) {
+		return p.MaxBackoff
+	}
+	return time.Duration(d)
+}
+
+func (p *RetryPolicy) retryable(kind FailureKind) bool {
+	switch kind {
+	case FailureNetwork, FailureServer, FailureRateLimited, FailureNonce:
+		return true
+	}
+	return false
+}
+
+// Attempt records one try of a transaction
+type Attempt struct {
+	Number int
+	Nonce  uint64
+	Kind   FailureKind
+	Err    error
+	// Backoff is the delay waited after this attempt
+	Backoff time.Duration
+}
+
+// RetryError is returned when a transaction failed after retries. It
+// unwraps to the error of the last attempt.
+type RetryError struct {
+	Attempts []Attempt
+}
+
+func (e *RetryError) Error() string {
+	last := e.Attempts[len(e.Attempts)-1]
+	return fmt.Sprintf("TransactionFailed after %d attempts (%s): %v", len(e.Attempts), last.Kind, last.Err)
+}
+
+func (e *RetryError) Unwrap() error {
+	return e.Attempts[len(e.Attempts)-1].Err
+}
+
+// Kind returns the failure kind of the last attempt
+func (e *RetryError) Kind() FailureKind {
+	return e.Attempts[len(e.Attempts)-1].Kind
+}
+
+func (rpc *ZKWasmAppRpc) sendWithRetry(ctx context.Context, signer Signer, cmd [4]*big.Int, record *TxRecord) (*TransactionResult, error) {
+	policy := rpc.retry
+	command, err := DecodeCommand(cmd[0])
+	if err != nil {
+		return nil, err
+	}
+	var attempts []Attempt
+	// mayHaveLanded is set once an attempt failed without a clear rejection
+	mayHaveLanded := false
+	for n := 1; ; n++ {
+		result, err := rpc.sendOnce(ctx, signer, cmd, record)
+		if err == nil {
+			return result, nil
+		}
+		attempt := Attempt{Number: n, Nonce: command.Nonce, Kind: ClassifyError(err), Err: err}
+		if attempt.Kind == FailureNonce && mayHaveLanded {
+			attempt.Kind = FailureOutcomeUnknown
+			attempt.Err = fmt.Errorf("%w: nonce %d conflicts after an attempt that may have landed: %w", ErrOutcomeUnknown, command.Nonce, err)
+		}
+		if attempt.Kind == FailureNetwork || attempt.Kind == FailureServer {
+			mayHaveLanded = true
+		}
+		if n >= policy.MaxAttempts || !policy.retryable(attempt.Kind) {
+			attempts = append(attempts, attempt)
+			return nil, &RetryError{Attempts: attempts}
+		}
+
+		attempt.Backoff = policy.backoff(n)
+		// the Retry-After of a 429 already holds the next request in
+		// roundTrip, so only other statuses stretch the backoff
+		var statusErr *StatusError
+		if errors.As(err, &statusErr) && statusErr.StatusCode != http.StatusTooManyRequests && statusErr.RetryAfter > attempt.Backoff {
+			attempt.Backoff = statusErr.RetryAfter
+		}
+		attempts = append(attempts, attempt)
+		if rpc.metrics != nil {
+			rpc.metrics.ObserveRetry(attempt.Kind)
+		}
+		select {
+		case <-ctx.Done():
+			attempts = append(attempts, Attempt{Number: n + 1, Nonce: command.Nonce, Kind: FailureCanceled, Err: ctx.Err()})
+			return nil, &RetryError{Attempts: attempts}
+		case <-time.After(attempt.Backoff):
+		}
+
+		if attempt.Kind == FailureNonce {
+			nonce, err := rpc.GetNoncePkx(ctx, signer.Pkx())
+			if err != nil {
+				attempts = append(attempts, Attempt{Number: n + 1, Nonce: command.Nonce, Kind: ClassifyError(err), Err: err})
+				return nil, &RetryError{Attempts: attempts}
+			}
+			command.Nonce = nonce.Uint64()
+			limb, err := command.Encode()
+			if err != nil {
+				return nil, err
+			}
+			cmd[0] = limb
+		}
+	}
+}
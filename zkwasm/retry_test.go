@@ -0,0 +1,72 @@
+package zkwasm
+
+import (
+	"context"
+	"errors"
+	"testing"
+	"time"
+)
+
+var fastRetry = RetryPolicy{MaxAttempts: 4, InitialBackoff: time.Millisecond, Multiplier: 1}
+
+// TestRetryLostResponseAppliesOnce covers a send the server accepted but
+// whose response was lost: the resent payload conflicts on its nonce, and
+// the client must not sign the command again with a fresh one
+func TestRetryLostResponseAppliesOnce(t *testing.T) {
+	fake, server := newFakeRollup(t)
+	fake.dropResponses = 1
+	rpc := NewZKWasmAppRpc(server.URL, WithRetry(fastRetry))
+
+	_, err := rpc.SendTransactionWithSigner(context.Background(), NewKeySigner("1234"), testCommand(0))
+	if !errors.Is(err, ErrOutcomeUnknown) {
+		t.Fatalf("err = %v, want ErrOutcomeUnknown", err)
+	}
+	var retryErr *RetryError
+	if !errors.As(err, &retryErr) || retryErr.Kind() != FailureOutcomeUnknown {
+		t.Fatalf("err = %#v, want a RetryError of kind outcome-unknown", err)
+	}
+	if got := retryErr.Attempts[0].Kind; got != FailureNetwork {
+		t.Fatalf("first attempt kind = %s, want network", got)
+	}
+	if fake.appliedCount() != 1 {
+		t.Fatalf("command applied %d times, want once", fake.appliedCount())
+	}
+}
+
+func TestRetryServerErrorResendsSamePayload(t *testing.T) {
+	fake, server := newFakeRollup(t)
+	fake.failSends = 2
+	rpc := NewZKWasmAppRpc(server.URL, WithRetry(fastRetry))
+
+	if _, err := rpc.SendTransactionWithSigner(context.Background(), NewKeySigner("1234"), testCommand(0)); err != nil {
+		t.Fatal(err)
+	}
+	if fake.sends != 3 || fake.appliedCount() != 1 {
+		t.Fatalf("sends = %d, applied = %d, want 3 and 1", fake.sends, fake.appliedCount())
+	}
+}
+
+func TestRetryStaleNonceResigns(t *testing.T) {
+	fake, server := newFakeRollup(t)
+	signer := NewKeySigner("1234")
+	fake.nonces[signer.Pkx()] = 5
+	rpc := NewZKWasmAppRpc(server.URL, WithRetry(fastRetry))
+
+	if _, err := rpc.SendTransactionWithSigner(context.Background(), signer, testCommand(2)); err != nil {
+		t.Fatal(err)
+	}
+	if fake.appliedCount() != 1 || fake.applied[0]>>16 != 5 {
+		t.Fatalf("applied = %v, want one command at nonce 5", fake.applied)
+	}
+}
+
+func TestRetryStopsOnApplicationFailure(t *testing.T) {
+	_, server := newFakeRollup(t)
+	rpc := NewZKWasmAppRpc(server.URL+"/missing", WithRetry(fastRetry))
+
+	_, err := rpc.SendTransactionWithSigner(context.Background(), NewKeySigner("1234"), testCommand(0))
+	var retryErr *RetryError
+	if !errors.As(err, &retryErr) || len(retryErr.Attempts) != 1 || retryErr.Kind() != FailureApplication {
+		t.Fatalf("err = %v, want a single application failure", err)
+	}
+}
@@ -1,166 +1,367 @@
 package zkwasm
 
 import (
-	"bytes"
+	"context"
 	"encoding/json"
 	"errors"
 	"fmt"
+	"io"
 	"math/big"
 	"net/http"
+	"strconv"
+	"strings"
 	"time"
 )
 
+var ErrMonitorTransactionFail = errors.New("MonitorTransactionFail")
+
+// StatusError reports an unexpected HTTP status from the rollup server
+type StatusError struct {
+	Op         string
+	StatusCode int
+	Body       string
+	// RetryAfter is the delay requested by a Retry-After header, if any
+	RetryAfter time.Duration
+}
+
+func (e *StatusError) Error() string {
+	if e.Body != "" {
+		return fmt.Sprintf("%s: status %d: %s", e.Op, e.StatusCode, e.Body)
+	}
+	return fmt.Sprintf("%s: status %d", e.Op, e.StatusCode)
+}
+
+func newStatusError(op string, resp *http.Response) *StatusError {
+	body, _ := io.ReadAll(io.LimitReader(resp.Body, 1024))
+	return &StatusError{
+		Op:         op,
+		StatusCode: resp.StatusCode,
+		Body:       strings.TrimSpace(string(body)),
+		RetryAfter: parseRetryAfter(resp.Header.Get("Retry-After")),
+	}
+}
+
+// parseRetryAfter reads a Retry-After header given in seconds or as an
+// HTTP date
+func parseRetryAfter(value string) time.Duration {
+	if value == "" {
+		return 0
+	}
+	if seconds, err := strconv.Atoi(value); err == nil && seconds > 0 {
+		return time.Duration(seconds) * time.Second
+	}
+	if at, err := http.ParseTime(value); err == nil {
+		if d := time.Until(at); d > 0 {
+			return d
+		}
+	}
+	return 0
+}
+
+// JobFailedError reports a transaction job rejected by the application
+type JobFailedError struct {
+	JobID  string
+	Reason string
+}
+
+func (e *JobFailedError) Error() string {
+	return e.Reason
+}
+
 type ZKWasmAppRpc struct {
-	baseURL string
-	client  *http.Client
+	baseURL  string
+	client   *http.Client
+	journal  TxJournal
+	retry    *RetryPolicy
+	limiter  *limiter
+	throttle throttle
+	metrics  Metrics
+	tracer   Tracer
+
+	interceptors []Interceptor
+	// handler is the interceptor chain around execute, built once the
+	// options are applied
+	handler Handler
+}
+
+// AppClient is the request API shared by ZKWasmAppRpc and ZKWasmPool, so
+// application clients can run on a single endpoint or a pool
+type AppClient interface {
+	QueryState(prikey string) (map[string]interface{}, error)
+	QueryStateContext(ctx context.Context, prikey string) (map[string]interface{}, error)
+	QueryStatePkx(ctx context.Context, pkx string) (map[string]interface{}, error)
+	QueryConfig() (map[string]interface{}, error)
+	QueryConfigContext(ctx context.Context) (map[string]interface{}, error)
+	GetNonce(prikey string) (*big.Int, error)
+	GetNonceContext(ctx context.Context, prikey string) (*big.Int, error)
+	GetNoncePkx(ctx context.Context, pkx string) (*big.Int, error)
+	SendTransaction(cmd [4]*big.Int, prikey string) (string, error)
+	SendTransactionResult(cmd [4]*big.Int, prikey string) (*TransactionResult, error)
+	SendTransactionResultContext(ctx context.Context, cmd [4]*big.Int, prikey string) (*TransactionResult, error)
+	SendTransactionWithSigner(ctx context.Context, signer Signer, cmd [4]*big.Int) (*TransactionResult, error)
+}
+
+var (
+	_ AppClient = (*ZKWasmAppRpc)(nil)
+	_ AppClient = (*ZKWasmPool)(nil)
+)
+
+// Option configures optional ZKWasmAppRpc behaviour
+type Option func(*ZKWasmAppRpc)
+
+// WithHTTPClient replaces the default http.Client
+func WithHTTPClient(client *http.Client) Option {
+	return func(rpc *ZKWasmAppRpc) {
+		rpc.client = client
+	}
 }
 
-func NewZKWasmAppRpc(baseURL string) *ZKWasmAppRpc {
-	return &ZKWasmAppRpc{
+// WithJournal records every transaction sent through the client in journal
+// so unfinished ones can be recovered with Recover after a restart
+func WithJournal(journal TxJournal) Option {
+	return func(rpc *ZKWasmAppRpc) {
+		rpc.journal = journal
+	}
+}
+
+func NewZKWasmAppRpc(baseURL string, opts ...Option) *ZKWasmAppRpc {
+	rpc := &ZKWasmAppRpc{
 		baseURL: baseURL,
 		client:  &http.Client{},
 	}
+	for _, opt := range opts {
+		opt(rpc)
+	}
+	rpc.handler = rpc.chain()
+	return rpc
+}
+
+func (rpc *ZKWasmAppRpc) postTransaction(ctx context.Context, data map[string]string) (map[string]interface{}, error) {
+	return rpc.do(ctx, &Call{Endpoint: EndpointSend, Method: http.MethodPost, Path: "/send", Payload: data})
+}
+
+// TransactionResult is the outcome of a finished transaction job
+type TransactionResult struct {
+	JobID       string
+	ReturnValue string
+}
+
+// Decode unmarshals the job return value into v
+func (r *TransactionResult) Decode(v interface{}) error {
+	return json.Unmarshal([]byte(r.ReturnValue), v)
 }
 
-func (rpc *ZKWasmAppRpc) sendRawTransaction(cmd [4]*big.Int, prikey string) (map[string]interface{}, error) {
-	data := Sign(cmd, prikey)
-	jsonData, err := json.Marshal(data)
+func (rpc *ZKWasmAppRpc) SendTransaction(cmd [4]*big.Int, prikey string) (string, error) {
+	result, err := rpc.SendTransactionResult(cmd, prikey)
 	if err != nil {
-		return nil, err
+		return "", err
 	}
-	resp, err := rpc.client.Post(fmt.Sprintf("%s/send", rpc.baseURL), "application/json", bytes.NewBuffer(jsonData))
+	return result.ReturnValue, nil
+}
+
+// SendTransactionResult sends a transaction and waits for its job like
+// SendTransaction, also reporting the job id assigned by the server
+func (rpc *ZKWasmAppRpc) SendTransactionResult(cmd [4]*big.Int, prikey string) (*TransactionResult, error) {
+	return rpc.SendTransactionResultContext(context.Background(), cmd, prikey)
+}
+
+// SendTransactionResultContext is SendTransactionResult with a context
+// bounding the send request and the job polling
+func (rpc *ZKWasmAppRpc) SendTransactionResultContext(ctx context.Context, cmd [4]*big.Int, prikey string) (*TransactionResult, error) {
+	return rpc.SendTransactionWithSigner(ctx, NewKeySigner(prikey), cmd)
+}
+
+// SendTransactionWithSigner sends a transaction signed by signer and waits
+// for its job, retrying according to the client RetryPolicy if one is set
+func (rpc *ZKWasmAppRpc) SendTransactionWithSigner(ctx context.Context, signer Signer, cmd [4]*big.Int) (result *TransactionResult, err error) {
+	ctx, span := rpc.startSpan(ctx, "zkwasm.transaction")
+	defer func() { endSpan(span, err) }()
+	if command, err := DecodeCommand(cmd[0]); err == nil {
+		span.SetAttribute(AttrCommandID, command.ID)
+	}
+	var record TxRecord
+	if rpc.retry != nil {
+		return rpc.sendWithRetry(ctx, signer, cmd, &record)
+	}
+	return rpc.sendOnce(ctx, signer, cmd, &record)
+}
+
+// sendOnce signs cmd, sends it and waits for its job. With a journal the
+// attempt is recorded in tx, which retries of the same transaction share;
+// failing to journal a finished job returns its result with the error.
+func (rpc *ZKWasmAppRpc) sendOnce(ctx context.Context, signer Signer, cmd [4]*big.Int, tx *TxRecord) (result *TransactionResult, err error) {
+	// a retry may re-sign with a fresh nonce, so each attempt carries its own
+	ctx, span := rpc.startSpan(ctx, "zkwasm.attempt")
+	defer func() { endSpan(span, err) }()
+	if command, err := DecodeCommand(cmd[0]); err == nil {
+		span.SetAttribute(AttrNonce, command.Nonce)
+	}
+
+	_, signSpan := rpc.startSpan(ctx, "zkwasm.sign")
+	signStart := time.Now()
+	data, err := signer.Sign(cmd)
+	endSpan(signSpan, err)
 	if err != nil {
 		return nil, err
 	}
-	defer resp.Body.Close()
-
-	if resp.StatusCode == http.StatusCreated {
-		var result map[string]interface{}
-		err = json.NewDecoder(resp.Body).Decode(&result)
-		if err != nil {
+	if rpc.metrics != nil {
+		rpc.metrics.ObserveSign(time.Since(signStart))
+	}
+	var record *TxRecord
+	if rpc.journal != nil {
+		record = tx
+		if err := record.signed(signer.Pkx(), cmd, data); err != nil {
+			return nil, err
+		}
+		if err := rpc.journal.Save(*record); err != nil {
 			return nil, err
 		}
-		return result, nil
 	}
-	return nil, errors.New("SendTransactionError")
-}
 
-func (rpc *ZKWasmAppRpc) SendTransaction(cmd [4]*big.Int, prikey string) (string, error) {
-	resp, err := rpc.sendRawTransaction(cmd, prikey)
+	sendCtx, sendSpan := rpc.startSpan(ctx, "zkwasm.send")
+	jobID, err := rpc.submit(sendCtx, data)
 	if err != nil {
-		return "", err
+		endSpan(sendSpan, err)
+		return nil, rpc.journalError(record, err)
 	}
-	fmt.Println("resp:", resp)
-	for i := 0; i < 5; i++ {
-		time.Sleep(1 * time.Second)
-		jobStatus, err := rpc.queryJobStatus(resp["jobid"].(string))
-		if err != nil {
-			continue
-		}
-		if jobStatus != nil {
-			if _, ok := jobStatus["finishedOn"]; ok && jobStatus["failedReason"] == nil {
-				returnValue := jobStatus["returnvalue"].(map[string]interface{})
-				marshal, jsonErr := json.Marshal(returnValue)
-				if jsonErr != nil {
-					return "", jsonErr
-				}
-				return string(marshal), nil
-			} else if jobStatus["failedReason"] != nil {
-				return "", errors.New(jobStatus["failedReason"].(string))
-			}
+	sendSpan.SetAttribute(AttrJobID, jobID)
+	sendSpan.End()
+	span.SetAttribute(AttrJobID, jobID)
+	if record != nil {
+		record.Status = TxSubmitted
+		record.JobID = jobID
+		if err := rpc.journal.Save(*record); err != nil {
+			return nil, err
 		}
 	}
-	return "", errors.New("MonitorTransactionFail")
+	result, err = rpc.waitJob(ctx, jobID)
+	return result, rpc.journalOutcome(record, result, err)
 }
 
-func (rpc *ZKWasmAppRpc) QueryState(prikey string) (map[string]interface{}, error) {
-	data := Query(prikey)
-	jsonData, err := json.Marshal(data)
+// submit posts a signed payload and returns the job id assigned to it
+func (rpc *ZKWasmAppRpc) submit(ctx context.Context, data map[string]string) (string, error) {
+	resp, err := rpc.postTransaction(ctx, data)
 	if err != nil {
-		return nil, err
+		return "", err
 	}
+	jobID, ok := resp["jobid"].(string)
+	if !ok {
+		return "", errors.New("SendTransactionError")
+	}
+	return jobID, nil
+}
 
-	resp, err := rpc.client.Post(fmt.Sprintf("%s/query", rpc.baseURL), "application/json", bytes.NewBuffer(jsonData))
-	if err != nil {
-		return nil, err
+// waitJob polls the job until it finishes, fails or the poll budget runs out
+func (rpc *ZKWasmAppRpc) waitJob(ctx context.Context, jobID string) (*TransactionResult, error) {
+	start := time.Now()
+	result, err := rpc.pollJob(ctx, jobID)
+	if rpc.metrics != nil {
+		rpc.metrics.ObserveJob(time.Since(start), err)
 	}
-	defer resp.Body.Close()
+	return result, err
+}
 
-	if resp.StatusCode == http.StatusCreated {
-		var result map[string]interface{}
-		err = json.NewDecoder(resp.Body).Decode(&result)
+func (rpc *ZKWasmAppRpc) pollJob(ctx context.Context, jobID string) (*TransactionResult, error) {
+	var pollErr error
+	for i := 0; i < 5; i++ {
+		select {
+		case <-ctx.Done():
+			return nil, ctx.Err()
+		case <-time.After(1 * time.Second):
+		}
+		pollCtx, span := rpc.startSpan(ctx, "zkwasm.poll")
+		span.SetAttribute(AttrJobID, jobID)
+		span.SetAttribute(AttrPoll, i+1)
+		jobStatus, err := rpc.queryJobStatus(pollCtx, jobID)
+		endSpan(span, err)
 		if err != nil {
-			return nil, err
+			pollErr = err
+			continue
+		}
+		result, err := jobResult(jobID, jobStatus)
+		if result != nil || err != nil {
+			return result, err
 		}
-		return result, nil
 	}
-	return nil, errors.New("UnexpectedResponseStatus")
+	if pollErr != nil {
+		return nil, fmt.Errorf("%w: job %s: last poll error: %v", ErrMonitorTransactionFail, jobID, pollErr)
+	}
+	return nil, fmt.Errorf("%w: job %s", ErrMonitorTransactionFail, jobID)
 }
 
-func (rpc *ZKWasmAppRpc) QueryConfig() (map[string]interface{}, error) {
-	resp, err := rpc.client.Post(fmt.Sprintf("%s/config", rpc.baseURL), "application/json", nil)
-	if err != nil {
-		return nil, err
+// jobResult interprets a job status, returning nil and no error while the
+// job is still running
+func jobResult(jobID string, jobStatus map[string]interface{}) (*TransactionResult, error) {
+	if jobStatus == nil {
+		return nil, nil
 	}
-	defer resp.Body.Close()
-
-	if resp.StatusCode == http.StatusCreated {
-		var result map[string]interface{}
-		err = json.NewDecoder(resp.Body).Decode(&result)
-		if err != nil {
-			return nil, err
+	if _, ok := jobStatus["finishedOn"]; ok && jobStatus["failedReason"] == nil {
+		returnValue, _ := jobStatus["returnvalue"].(map[string]interface{})
+		marshal, jsonErr := json.Marshal(returnValue)
+		if jsonErr != nil {
+			return nil, jsonErr
 		}
-		return result, nil
+		return &TransactionResult{JobID: jobID, ReturnValue: string(marshal)}, nil
+	} else if jobStatus["failedReason"] != nil {
+		return nil, &JobFailedError{JobID: jobID, Reason: fmt.Sprint(jobStatus["failedReason"])}
 	}
-	return nil, errors.New("QueryConfigError")
+	return nil, nil
+}
+
+func (rpc *ZKWasmAppRpc) QueryState(prikey string) (map[string]interface{}, error) {
+	return rpc.QueryStateContext(context.Background(), prikey)
+}
+
+// QueryStateContext is QueryState with a context bounding the request
+func (rpc *ZKWasmAppRpc) QueryStateContext(ctx context.Context, prikey string) (map[string]interface{}, error) {
+	return rpc.QueryStatePkx(ctx, Query(prikey)["pkx"])
+}
+
+// QueryStatePkx queries the state of the player identified by its public
+// key x coordinate, in the little-endian hex form produced by Query
+func (rpc *ZKWasmAppRpc) QueryStatePkx(ctx context.Context, pkx string) (map[string]interface{}, error) {
+	data := map[string]string{"pkx": pkx}
+	return rpc.do(ctx, &Call{Endpoint: EndpointQuery, Method: http.MethodPost, Path: "/query", Payload: data})
+}
+
+func (rpc *ZKWasmAppRpc) QueryConfig() (map[string]interface{}, error) {
+	return rpc.QueryConfigContext(context.Background())
 }
 
-func (rpc *ZKWasmAppRpc) CreateCommand(nonce, command, objindex *big.Int) *big.Int {
-	bigNonce0 := new(big.Int).Lsh(nonce, 16) // cmd[1] << 16
-	bigObj2 := new(big.Int).Lsh(objindex, 8) // cmd[3] << 0
-	cmd := new(big.Int).Add(bigNonce0, bigObj2)
-	cmd = cmd.Add(cmd, command)
-	return cmd
+// QueryConfigContext is QueryConfig with a context bounding the request
+func (rpc *ZKWasmAppRpc) QueryConfigContext(ctx context.Context) (map[string]interface{}, error) {
+	return rpc.do(ctx, &Call{Endpoint: EndpointConfig, Method: http.MethodPost, Path: "/config"})
 }
 
-func (rpc *ZKWasmAppRpc) queryJobStatus(jobID string) (map[string]interface{}, error) {
-	resp, err := rpc.client.Get(fmt.Sprintf("%s/job/%s", rpc.baseURL, jobID))
+// CreateCommand packs nonce, command id and object index into the first
+// transaction limb, rejecting values wider than their Command field
+func (rpc *ZKWasmAppRpc) CreateCommand(nonce, command, objindex *big.Int) (*big.Int, error) {
+	cmd, err := NewCommand(nonce, command, objindex)
 	if err != nil {
 		return nil, err
 	}
-	defer resp.Body.Close()
+	return cmd.Encode()
+}
 
-	if resp.StatusCode == http.StatusCreated {
-		var result map[string]interface{}
-		err = json.NewDecoder(resp.Body).Decode(&result)
-		if err != nil {
-			return nil, err
-		}
-		return result, nil
-	}
-	return nil, errors.New("QueryJobError")
+func (rpc *ZKWasmAppRpc) queryJobStatus(ctx context.Context, jobID string) (map[string]interface{}, error) {
+	return rpc.do(ctx, &Call{Endpoint: EndpointJob, Method: http.MethodGet, Path: "/job/" + jobID})
 }
 
 func (rpc *ZKWasmAppRpc) GetNonce(prikey string) (*big.Int, error) {
-	state, err := rpc.QueryState(prikey)
-	if err != nil {
-		return big.NewInt(0), err
-	}
+	return rpc.GetNonceContext(context.Background(), prikey)
+}
+
+// GetNonceContext is GetNonce with a context bounding the state query
+func (rpc *ZKWasmAppRpc) GetNonceContext(ctx context.Context, prikey string) (*big.Int, error) {
+	return rpc.GetNoncePkx(ctx, Query(prikey)["pkx"])
+}
 
-	var data map[string]interface{}
-	err = json.Unmarshal([]byte(state["data"].(string)), &data)
+// GetNoncePkx returns the nonce of the player identified by pkx, zero
+// when the player has not been created yet
+func (rpc *ZKWasmAppRpc) GetNoncePkx(ctx context.Context, pkx string) (*big.Int, error) {
+	state, err := rpc.QueryStatePkx(ctx, pkx)
 	if err != nil {
-		return big.NewInt(0), err
-	}
-	player, ok := data["player"]
-	if !ok {
-		fmt.Println("player field does not exist")
-		return big.NewInt(0), nil
-	} else if player == nil {
-		return big.NewInt(0), nil
-	} else {
-		playerMap := player.(map[string]interface{})
-		fmt.Println("player:", player)
-		return big.NewInt(int64(playerMap["nonce"].(float64))), nil
+		return nil, err
 	}
+	return decodeNonce(state)
 }
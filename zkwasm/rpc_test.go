@@ -0,0 +1,117 @@
+package zkwasm
+
+import (
+	"encoding/json"
+	"fmt"
+	"math/big"
+	"net/http"
+	"net/http/httptest"
+	"strings"
+	"sync"
+	"testing"
+)
+
+// fakeRollup is an in-memory rollup server holding one nonce per player.
+// A send applies when its nonce matches the player's and finishes its job
+// at once; any other nonce is rejected like the real server does.
+type fakeRollup struct {
+	mu     sync.Mutex
+	nonces map[string]uint64
+	// applied lists the command limbs applied, in order, and messages the
+	// whole transaction messages they came from
+	applied  []uint64
+	messages []*big.Int
+	jobs     map[string]uint64
+	sends    int
+	// dropResponses counts sends to apply whose response is lost by
+	// closing the connection
+	dropResponses int
+	// failSends counts sends to answer with a 503 without applying them
+	failSends int
+	// config is the application config served JSON encoded by /config,
+	// which answers 404 while it is nil
+	config map[string]interface{}
+}
+
+func newFakeRollup(t *testing.T) (*fakeRollup, *httptest.Server) {
+	f := &fakeRollup{nonces: make(map[string]uint64), jobs: make(map[string]uint64)}
+	server := httptest.NewServer(f)
+	t.Cleanup(server.Close)
+	return f, server
+}
+
+func (f *fakeRollup) ServeHTTP(w http.ResponseWriter, r *http.Request) {
+	var payload map[string]string
+	if r.Method == http.MethodPost {
+		json.NewDecoder(r.Body).Decode(&payload)
+	}
+	f.mu.Lock()
+	defer f.mu.Unlock()
+	switch {
+	case r.URL.Path == "/query":
+		data, _ := json.Marshal(map[string]interface{}{"player": map[string]interface{}{"nonce": f.nonces[payload["pkx"]]}})
+		reply(w, map[string]interface{}{"success": true, "data": string(data)})
+	case r.URL.Path == "/send":
+		f.send(w, payload)
+	case r.URL.Path == "/config" && f.config != nil:
+		data, _ := json.Marshal(f.config)
+		reply(w, map[string]interface{}{"success": true, "data": string(data)})
+	case strings.HasPrefix(r.URL.Path, "/job/"):
+		limb, ok := f.jobs[strings.TrimPrefix(r.URL.Path, "/job/")]
+		if !ok {
+			http.Error(w, "no such job", http.StatusNotFound)
+			return
+		}
+		reply(w, map[string]interface{}{"finishedOn": 1, "returnvalue": map[string]interface{}{"limb": limb}})
+	default:
+		http.NotFound(w, r)
+	}
+}
+
+func (f *fakeRollup) send(w http.ResponseWriter, payload map[string]string) {
+	f.sends++
+	if f.failSends > 0 {
+		f.failSends--
+		http.Error(w, "unavailable", http.StatusServiceUnavailable)
+		return
+	}
+	// the first limb is the low 64 bits of the message
+	limb := new(big.Int).And(LittleEndianHexToInt(payload["msg"]), new(big.Int).SetUint64(^uint64(0))).Uint64()
+	pkx := payload["pkx"]
+	if nonce := limb >> 16; nonce != f.nonces[pkx] {
+		http.Error(w, fmt.Sprintf("invalid nonce %d, expected %d", nonce, f.nonces[pkx]), http.StatusBadRequest)
+		return
+	}
+	f.nonces[pkx]++
+	f.applied = append(f.applied, limb)
+	f.messages = append(f.messages, LittleEndianHexToInt(payload["msg"]))
+	jobID := fmt.Sprint(len(f.jobs) + 1)
+	f.jobs[jobID] = limb
+	if f.dropResponses > 0 {
+		f.dropResponses--
+		conn, _, err := w.(http.Hijacker).Hijack()
+		if err == nil {
+			conn.Close()
+		}
+		return
+	}
+	reply(w, map[string]interface{}{"success": true, "jobid": jobID})
+}
+
+func reply(w http.ResponseWriter, v interface{}) {
+	w.Header().Set("Content-Type", "application/json")
+	w.WriteHeader(http.StatusCreated)
+	json.NewEncoder(w).Encode(v)
+}
+
+func (f *fakeRollup) appliedCount() int {
+	f.mu.Lock()
+	defer f.mu.Unlock()
+	return len(f.applied)
+}
+
+// testCommand returns the limbs of command 1 at nonce
+func testCommand(nonce uint64) [4]*big.Int {
+	limb, _ := (&Command{Nonce: nonce, ID: 1}).Encode()
+	return [4]*big.Int{limb, big.NewInt(0), big.NewInt(0), big.NewInt(0)}
+}
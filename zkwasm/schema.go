@@ -0,0 +1,265 @@
+package zkwasm
+
+import (
+	"encoding/json"
+	"errors"
+	"fmt"
+	"io"
+	"math/big"
+	"os"
+)
+
+var (
+	ErrInvalidSchema   = errors.New("InvalidSchema")
+	ErrUnknownCommand  = errors.New("UnknownCommand")
+	ErrInvalidArgument = errors.New("InvalidArgument")
+)
+
+// Schema describes the commands of a zkWasm application and how their
+// named parameters are packed into the transaction limbs.
+//
+// A schema is usually loaded from JSON:
+//
+//	{
+//	  "commands": [
+//	    {"name": "BuyElf", "id": 2, "params": [
+//	      {"name": "ranchId", "limb": 1},
+//	      {"name": "elfType", "limb": 2}
+//	    ]},
+//	    {"name": "Deposit", "id": 8, "admin": true, "params": [
+//	      {"name": "pid1", "limb": 1},
+//	      {"name": "pid2", "limb": 2},
+//	      {"name": "ranchId", "limb": 3, "offset": 32, "bits": 32},
+//	      {"name": "propType", "limb": 3, "bits": 32}
+//	    ]}
+//	  ]
+//	}
+type Schema struct {
+	Commands []*CommandSchema `json:"commands"`
+}
+
+// CommandSchema describes a single command. Limb 0 always carries the
+// encoded Command; parameters occupy bit ranges of limbs 1 to 3. Admin
+// marks commands the application only accepts from the admin key.
+type CommandSchema struct {
+	Name     string         `json:"name"`
+	ID       uint64         `json:"id"`
+	ObjIndex uint64         `json:"objIndex,omitempty"`
+	Admin    bool           `json:"admin,omitempty"`
+	Params   []*ParamSchema `json:"params,omitempty"`
+}
+
+// ParamSchema places a named parameter at Offset within a u64 limb. Bits
+// defaults to the rest of the limb when omitted.
+type ParamSchema struct {
+	Name   string `json:"name"`
+	Limb   int    `json:"limb"`
+	Offset uint   `json:"offset,omitempty"`
+	Bits   uint   `json:"bits,omitempty"`
+}
+
+// ParseSchema decodes and validates a JSON schema
+func ParseSchema(data []byte) (*Schema, error) {
+	var schema Schema
+	if err := json.Unmarshal(data, &schema); err != nil {
+		return nil, fmt.Errorf("%w: %v", ErrInvalidSchema, err)
+	}
+	if err := schema.Validate(); err != nil {
+		return nil, err
+	}
+	return &schema, nil
+}
+
+// LoadSchema reads a JSON schema from r
+func LoadSchema(r io.Reader) (*Schema, error) {
+	data, err := io.ReadAll(r)
+	if err != nil {
+		return nil, err
+	}
+	return ParseSchema(data)
+}
+
+// LoadSchemaFile reads a JSON schema from the named file
+func LoadSchemaFile(path string) (*Schema, error) {
+	data, err := os.ReadFile(path)
+	if err != nil {
+		return nil, err
+	}
+	return ParseSchema(data)
+}
+
+// Validate checks command ids and names are unique and that every command
+// layout is well formed
+func (s *Schema) Validate() error {
+	names := make(map[string]bool)
+	ids := make(map[uint64]bool)
+	for _, cs := range s.Commands {
+		if cs == nil {
+			return fmt.Errorf("%w: empty command", ErrInvalidSchema)
+		}
+		if names[cs.Name] {
+			return fmt.Errorf("%w: duplicate command name %q", ErrInvalidSchema, cs.Name)
+		}
+		if ids[cs.ID] {
+			return fmt.Errorf("%w: duplicate command id %d", ErrInvalidSchema, cs.ID)
+		}
+		names[cs.Name] = true
+		ids[cs.ID] = true
+		if err := cs.Validate(); err != nil {
+			return err
+		}
+	}
+	return nil
+}
+
+// Lookup returns the command with the given name
+func (s *Schema) Lookup(name string) (*CommandSchema, error) {
+	for _, cs := range s.Commands {
+		if cs.Name == name {
+			return cs, nil
+		}
+	}
+	return nil, fmt.Errorf("%w: %s", ErrUnknownCommand, name)
+}
+
+// LookupID returns the command with the given id
+func (s *Schema) LookupID(id uint64) (*CommandSchema, error) {
+	for _, cs := range s.Commands {
+		if cs.ID == id {
+			return cs, nil
+		}
+	}
+	return nil, fmt.Errorf("%w: id %d", ErrUnknownCommand, id)
+}
+
+// Decode identifies the command carried by limbs and decodes its arguments
+func (s *Schema) Decode(limbs [4]*big.Int) (*CommandSchema, *Command, map[string]*big.Int, error) {
+	cmd, err := DecodeCommand(limbs[0])
+	if err != nil {
+		return nil, nil, nil, err
+	}
+	cs, err := s.LookupID(cmd.ID)
+	if err != nil {
+		return nil, nil, nil, err
+	}
+	_, args, err := cs.Decode(limbs)
+	if err != nil {
+		return nil, nil, nil, err
+	}
+	return cs, cmd, args, nil
+}
+
+func (p *ParamSchema) width() uint {
+	if p.Bits == 0 {
+		return 64 - p.Offset
+	}
+	return p.Bits
+}
+
+func (p *ParamSchema) mask() uint64 {
+	if p.width() == 64 {
+		return ^uint64(0)
+	}
+	return (uint64(1) << p.width()) - 1
+}
+
+// Validate checks the command id and that parameters fit their limbs
+// without overlapping
+func (cs *CommandSchema) Validate() error {
+	if cs.Name == "" {
+		return fmt.Errorf("%w: command without a name", ErrInvalidSchema)
+	}
+	if cs.ID>>CommandIDBits != 0 || cs.ObjIndex>>CommandObjIndexBits != 0 {
+		return fmt.Errorf("%w: %s id or objIndex does not fit in 8 bits", ErrInvalidSchema, cs.Name)
+	}
+	var used [4]uint64
+	names := make(map[string]bool)
+	for _, p := range cs.Params {
+		if p == nil || p.Name == "" {
+			return fmt.Errorf("%w: %s has a parameter without a name", ErrInvalidSchema, cs.Name)
+		}
+		if names[p.Name] {
+			return fmt.Errorf("%w: %s has duplicate parameter %q", ErrInvalidSchema, cs.Name, p.Name)
+		}
+		names[p.Name] = true
+		if p.Limb < 1 || p.Limb > 3 {
+			return fmt.Errorf("%w: %s.%s must use limb 1, 2 or 3", ErrInvalidSchema, cs.Name, p.Name)
+		}
+		if p.Offset >= 64 || p.Offset+p.width() > 64 {
+			return fmt.Errorf("%w: %s.%s does not fit in a u64 limb", ErrInvalidSchema, cs.Name, p.Name)
+		}
+		bitsUsed := p.mask() << p.Offset
+		if used[p.Limb]&bitsUsed != 0 {
+			return fmt.Errorf("%w: %s.%s overlaps another parameter", ErrInvalidSchema, cs.Name, p.Name)
+		}
+		used[p.Limb] |= bitsUsed
+	}
+	return nil
+}
+
+// Encode packs nonce and the named arguments into transaction limbs ready
+// for SendTransaction. Every parameter must be supplied and in range.
+func (cs *CommandSchema) Encode(nonce *big.Int, args map[string]*big.Int) ([4]*big.Int, error) {
+	var limbs [4]*big.Int
+	cmd, err := NewCommand(nonce, new(big.Int).SetUint64(cs.ID), new(big.Int).SetUint64(cs.ObjIndex))
+	if err != nil {
+		return limbs, err
+	}
+	limbs[0], err = cmd.Encode()
+	if err != nil {
+		return limbs, err
+	}
+
+	for name := range args {
+		if cs.param(name) == nil {
+			return limbs, fmt.Errorf("%w: %s has no parameter %q", ErrInvalidArgument, cs.Name, name)
+		}
+	}
+	var packed [4]uint64
+	for _, p := range cs.Params {
+		value, ok := args[p.Name]
+		if !ok || value == nil {
+			return limbs, fmt.Errorf("%w: %s.%s is missing", ErrInvalidArgument, cs.Name, p.Name)
+		}
+		if value.Sign() < 0 || value.BitLen() > int(p.width()) {
+			return limbs, fmt.Errorf("%w: %s.%s does not fit in %d bits", ErrInvalidArgument, cs.Name, p.Name, p.width())
+		}
+		packed[p.Limb] |= value.Uint64() << p.Offset
+	}
+	for i := 1; i < 4; i++ {
+		limbs[i] = new(big.Int).SetUint64(packed[i])
+	}
+	return limbs, nil
+}
+
+// Decode splits transaction limbs back into the command and its named
+// arguments, checking the command id matches the schema
+func (cs *CommandSchema) Decode(limbs [4]*big.Int) (*Command, map[string]*big.Int, error) {
+	cmd, err := DecodeCommand(limbs[0])
+	if err != nil {
+		return nil, nil, err
+	}
+	if cmd.ID != cs.ID {
+		return nil, nil, fmt.Errorf("%w: expected %s (id %d), got id %d", ErrUnknownCommand, cs.Name, cs.ID, cmd.ID)
+	}
+	for i := 1; i < 4; i++ {
+		if limbs[i] == nil || limbs[i].Sign() < 0 || limbs[i].BitLen() > 64 {
+			return nil, nil, fmt.Errorf("%w: limb %d is not a u64", ErrInvalidArgument, i)
+		}
+	}
+	args := make(map[string]*big.Int)
+	for _, p := range cs.Params {
+		value := (limbs[p.Limb].Uint64() >> p.Offset) & p.mask()
+		args[p.Name] = new(big.Int).SetUint64(value)
+	}
+	return cmd, args, nil
+}
+
+func (cs *CommandSchema) param(name string) *ParamSchema {
+	for _, p := range cs.Params {
+		if p.Name == name {
+			return p
+		}
+	}
+	return nil
+}
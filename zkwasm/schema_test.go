@@ -0,0 +1,157 @@
+package zkwasm
+
+import (
+	"errors"
+	"math/big"
+	"strings"
+	"testing"
+)
+
+const testSchemaJSON = `{
+  "commands": [
+    {"name": "BuyElf", "id": 2, "params": [
+      {"name": "ranchId", "limb": 1},
+      {"name": "elfType", "limb": 2}
+    ]},
+    {"name": "Deposit", "id": 8, "objIndex": 3, "admin": true, "params": [
+      {"name": "pid1", "limb": 1},
+      {"name": "pid2", "limb": 2},
+      {"name": "ranchId", "limb": 3, "offset": 32, "bits": 32},
+      {"name": "propType", "limb": 3, "bits": 32}
+    ]}
+  ]
+}`
+
+func testSchema(t *testing.T) *Schema {
+	t.Helper()
+	schema, err := LoadSchema(strings.NewReader(testSchemaJSON))
+	if err != nil {
+		t.Fatal(err)
+	}
+	return schema
+}
+
+func TestSchemaEncodeDecodeRoundTrip(t *testing.T) {
+	schema := testSchema(t)
+	deposit, err := schema.Lookup("Deposit")
+	if err != nil {
+		t.Fatal(err)
+	}
+	args := map[string]*big.Int{
+		"pid1":     new(big.Int).SetUint64(^uint64(0)),
+		"pid2":     big.NewInt(2),
+		"ranchId":  big.NewInt(0xffffffff),
+		"propType": big.NewInt(5),
+	}
+	limbs, err := deposit.Encode(big.NewInt(7), args)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if want := new(big.Int).SetUint64(0xffffffff<<32 | 5); limbs[3].Cmp(want) != 0 {
+		t.Fatalf("limb 3 = %#x, want %#x", limbs[3], want)
+	}
+
+	cs, cmd, decoded, err := schema.Decode(limbs)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if cs != deposit || cmd.Nonce != 7 || cmd.ID != 8 || cmd.ObjIndex != 3 {
+		t.Fatalf("decoded %s %v", cs.Name, cmd)
+	}
+	if len(decoded) != len(args) {
+		t.Fatalf("decoded %d args, want %d", len(decoded), len(args))
+	}
+	for name, want := range args {
+		if decoded[name].Cmp(want) != 0 {
+			t.Errorf("%s = %s, want %s", name, decoded[name], want)
+		}
+	}
+}
+
+func TestSchemaEncodeErrors(t *testing.T) {
+	deposit, err := testSchema(t).Lookup("Deposit")
+	if err != nil {
+		t.Fatal(err)
+	}
+	valid := func() map[string]*big.Int {
+		return map[string]*big.Int{"pid1": big.NewInt(1), "pid2": big.NewInt(2), "ranchId": big.NewInt(3), "propType": big.NewInt(4)}
+	}
+	cases := []struct {
+		name  string
+		edit  func(map[string]*big.Int)
+		nonce *big.Int
+		want  error
+	}{
+		{"overflowing field", func(a map[string]*big.Int) { a["ranchId"] = new(big.Int).Lsh(big.NewInt(1), 32) }, big.NewInt(0), ErrInvalidArgument},
+		{"overflowing limb", func(a map[string]*big.Int) { a["pid1"] = new(big.Int).Lsh(big.NewInt(1), 64) }, big.NewInt(0), ErrInvalidArgument},
+		{"negative", func(a map[string]*big.Int) { a["propType"] = big.NewInt(-1) }, big.NewInt(0), ErrInvalidArgument},
+		{"missing", func(a map[string]*big.Int) { delete(a, "pid2") }, big.NewInt(0), ErrInvalidArgument},
+		{"unknown", func(a map[string]*big.Int) { a["elfType"] = big.NewInt(1) }, big.NewInt(0), ErrInvalidArgument},
+		{"overflowing nonce", func(map[string]*big.Int) {}, new(big.Int).Lsh(big.NewInt(1), CommandNonceBits), ErrCommandFieldOverflow},
+	}
+	for _, c := range cases {
+		args := valid()
+		c.edit(args)
+		if _, err := deposit.Encode(c.nonce, args); !errors.Is(err, c.want) {
+			t.Errorf("%s: err = %v, want %v", c.name, err, c.want)
+		}
+	}
+}
+
+func TestSchemaDecodeErrors(t *testing.T) {
+	schema := testSchema(t)
+	buyElf, err := schema.Lookup("BuyElf")
+	if err != nil {
+		t.Fatal(err)
+	}
+	limbs, err := buyElf.Encode(big.NewInt(1), map[string]*big.Int{"ranchId": big.NewInt(1), "elfType": big.NewInt(2)})
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	unknown := limbs
+	unknown[0] = big.NewInt(1<<16 | 99)
+	if _, _, _, err := schema.Decode(unknown); !errors.Is(err, ErrUnknownCommand) {
+		t.Fatalf("unknown id err = %v", err)
+	}
+	deposit, _ := schema.Lookup("Deposit")
+	if _, _, err := deposit.Decode(limbs); !errors.Is(err, ErrUnknownCommand) {
+		t.Fatalf("mismatched command err = %v", err)
+	}
+	wide := limbs
+	wide[2] = new(big.Int).Lsh(big.NewInt(1), 64)
+	if _, _, err := buyElf.Decode(wide); !errors.Is(err, ErrInvalidArgument) {
+		t.Fatalf("wide limb err = %v", err)
+	}
+	if _, err := schema.Lookup("SellElf"); !errors.Is(err, ErrUnknownCommand) {
+		t.Fatalf("lookup err = %v", err)
+	}
+}
+
+func TestSchemaValidate(t *testing.T) {
+	cases := map[string]string{
+		"duplicate name":     `{"commands": [{"name": "A", "id": 1}, {"name": "A", "id": 2}]}`,
+		"duplicate id":       `{"commands": [{"name": "A", "id": 1}, {"name": "B", "id": 1}]}`,
+		"empty command":      `{"commands": [null]}`,
+		"unnamed command":    `{"commands": [{"id": 1}]}`,
+		"wide id":            `{"commands": [{"name": "A", "id": 256}]}`,
+		"wide objIndex":      `{"commands": [{"name": "A", "id": 1, "objIndex": 256}]}`,
+		"unnamed param":      `{"commands": [{"name": "A", "id": 1, "params": [{"limb": 1}]}]}`,
+		"duplicate param":    `{"commands": [{"name": "A", "id": 1, "params": [{"name": "x", "limb": 1, "bits": 8}, {"name": "x", "limb": 2}]}]}`,
+		"command limb":       `{"commands": [{"name": "A", "id": 1, "params": [{"name": "x", "limb": 0}]}]}`,
+		"limb out of range":  `{"commands": [{"name": "A", "id": 1, "params": [{"name": "x", "limb": 4}]}]}`,
+		"overflowing param":  `{"commands": [{"name": "A", "id": 1, "params": [{"name": "x", "limb": 1, "offset": 40, "bits": 32}]}]}`,
+		"offset past limb":   `{"commands": [{"name": "A", "id": 1, "params": [{"name": "x", "limb": 1, "offset": 64}]}]}`,
+		"overlapping params": `{"commands": [{"name": "A", "id": 1, "params": [{"name": "x", "limb": 1, "bits": 16}, {"name": "y", "limb": 1, "offset": 8}]}]}`,
+		"malformed json":     `{"commands": [`,
+		"wrong field type":   `{"commands": [{"name": "A", "id": "1"}]}`,
+	}
+	for name, data := range cases {
+		if _, err := ParseSchema([]byte(data)); !errors.Is(err, ErrInvalidSchema) {
+			t.Errorf("%s: err = %v, want ErrInvalidSchema", name, err)
+		}
+	}
+	if _, err := ParseSchema([]byte(`{"commands": [{"name": "A", "id": 1, "params": [{"name": "x", "limb": 1, "bits": 32}, {"name": "y", "limb": 1, "offset": 32}]}]}`)); err != nil {
+		t.Fatalf("adjacent params: %v", err)
+	}
+}
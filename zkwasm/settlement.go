@@ -0,0 +1,220 @@
+package zkwasm
+
+import (
+	"errors"
+	"fmt"
+	"math/big"
+	"strconv"
+	"strings"
+)
+
+var (
+	ErrInvalidSettlementData = errors.New("InvalidSettlementData")
+	ErrDuplicateWithdrawal   = errors.New("DuplicateWithdrawal")
+)
+
+// withdrawRecordSize is the length of one withdrawal in settlement tx data:
+// 4 bytes of op/index header, a 20-byte address and an 8-byte amount
+const withdrawRecordSize = 32
+
+// WithdrawRecord is a single withdrawal carried by a settlement batch
+type WithdrawRecord struct {
+	Op      byte
+	Index   byte
+	Address string
+	Amount  *big.Int
+}
+
+// SubmittedWithdrawal describes a withdraw command we sent to the rollup
+type SubmittedWithdrawal struct {
+	Pid1    *big.Int
+	Pid2    *big.Int
+	JobID   string
+	Address string
+	Amount  *big.Int
+}
+
+// WithdrawMatch pairs a submitted withdrawal with the settlement record
+// that paid it out
+type WithdrawMatch struct {
+	Submitted SubmittedWithdrawal
+	Record    WithdrawRecord
+	// Ambiguous is set when a submission from another player or job has the
+	// same address and amount, so the record could have paid either of them
+	Ambiguous bool
+}
+
+// SettlementBatch is the decoded withdrawal section of a settlement
+type SettlementBatch struct {
+	Records []WithdrawRecord
+}
+
+// Reconciliation is the outcome of matching submitted withdrawals against
+// a settlement batch
+type Reconciliation struct {
+	Matched []WithdrawMatch
+	// Pending holds submissions with no corresponding record in the batch
+	Pending []SubmittedWithdrawal
+	// Unexpected holds records that match none of the submissions
+	Unexpected []WithdrawRecord
+}
+
+// bytesToDecimal prints every byte as a zero-padded decimal and joins the
+// digits, which is how the rollup's reference decoder reads amounts
+func bytesToDecimal(bytes []byte) string {
+	var sb strings.Builder
+	for _, b := range bytes {
+		sb.WriteString(fmt.Sprintf("%02d", b))
+	}
+	return sb.String()
+}
+
+// DecodeWithdrawRecords parses the 32-byte withdrawal records of settlement
+// tx data. Addresses are returned EIP-55 checksummed. Amounts keep the
+// reading of the original decodeWithdraw: the eight amount bytes are
+// rendered with bytesToDecimal and parsed as a base-10 int64.
+func DecodeWithdrawRecords(txdata []byte) ([]WithdrawRecord, error) {
+	if len(txdata) <= 1 {
+		return nil, nil
+	}
+	if len(txdata)%withdrawRecordSize != 0 {
+		return nil, fmt.Errorf("%w: length %d is not a multiple of %d", ErrInvalidSettlementData, len(txdata), withdrawRecordSize)
+	}
+	var records []WithdrawRecord
+	for i := 0; i < len(txdata); i += withdrawRecordSize {
+		extra := txdata[i : i+4]
+		address := txdata[i+4 : i+24]
+		amount, err := strconv.ParseInt(bytesToDecimal(txdata[i+24:i+32]), 10, 64)
+		if err != nil {
+			return nil, fmt.Errorf("%w: record %d amount: %v", ErrInvalidSettlementData, i/withdrawRecordSize, err)
+		}
+		records = append(records, WithdrawRecord{
+			Op:      extra[0],
+			Index:   extra[1],
+			Address: ChecksumAddress(address),
+			Amount:  big.NewInt(amount),
+		})
+	}
+	return records, nil
+}
+
+// InspectSettlement decodes the withdrawals carried by settlement tx data
+func InspectSettlement(txdata []byte) (*SettlementBatch, error) {
+	records, err := DecodeWithdrawRecords(txdata)
+	if err != nil {
+		return nil, err
+	}
+	return &SettlementBatch{Records: records}, nil
+}
+
+// Addresses returns the distinct L1 addresses paid by the batch in order
+func (b *SettlementBatch) Addresses() []string {
+	var addresses []string
+	seen := make(map[string]bool)
+	for _, record := range b.Records {
+		if !seen[record.Address] {
+			seen[record.Address] = true
+			addresses = append(addresses, record.Address)
+		}
+	}
+	return addresses
+}
+
+// ByAddress groups the batch records by L1 address
+func (b *SettlementBatch) ByAddress() map[string][]WithdrawRecord {
+	groups := make(map[string][]WithdrawRecord)
+	for _, record := range b.Records {
+		groups[record.Address] = append(groups[record.Address], record)
+	}
+	return groups
+}
+
+// Totals sums the withdrawn amount per L1 address
+func (b *SettlementBatch) Totals() map[string]*big.Int {
+	totals := make(map[string]*big.Int)
+	for _, record := range b.Records {
+		if _, ok := totals[record.Address]; !ok {
+			totals[record.Address] = big.NewInt(0)
+		}
+		totals[record.Address].Add(totals[record.Address], record.Amount)
+	}
+	return totals
+}
+
+// Total sums every withdrawal in the batch
+func (b *SettlementBatch) Total() *big.Int {
+	total := big.NewInt(0)
+	for _, record := range b.Records {
+		total.Add(total, record.Amount)
+	}
+	return total
+}
+
+// Reconcile matches submitted withdrawals to batch records by address and
+// amount. Settlement records carry no player or job id, so each record pays
+// at most one submission, earliest first, and matches whose address and
+// amount are shared with a submission from another pid or job are flagged
+// Ambiguous. Submissions must have distinct job ids.
+func (b *SettlementBatch) Reconcile(submitted []SubmittedWithdrawal) (*Reconciliation, error) {
+	type key struct{ address, amount string }
+	keys := make([]key, len(submitted))
+	owners := make(map[key]map[string]bool)
+	jobs := make(map[string]bool)
+	for i, sub := range submitted {
+		if sub.JobID != "" {
+			if jobs[sub.JobID] {
+				return nil, fmt.Errorf("%w: job %s", ErrDuplicateWithdrawal, sub.JobID)
+			}
+			jobs[sub.JobID] = true
+		}
+		addressBytes, err := ParseAddress(sub.Address)
+		if err != nil {
+			return nil, err
+		}
+		if sub.Amount == nil {
+			return nil, fmt.Errorf("%w: job %s has no amount", ErrAmountOutOfRange, sub.JobID)
+		}
+		keys[i] = key{ChecksumAddress(addressBytes), sub.Amount.String()}
+		if owners[keys[i]] == nil {
+			owners[keys[i]] = make(map[string]bool)
+		}
+		owners[keys[i]][submissionOwner(sub)] = true
+	}
+
+	used := make([]bool, len(b.Records))
+	result := &Reconciliation{}
+	for i, sub := range submitted {
+		matched := false
+		for j, record := range b.Records {
+			if used[j] || record.Address != keys[i].address || record.Amount.String() != keys[i].amount {
+				continue
+			}
+			used[j] = true
+			matched = true
+			result.Matched = append(result.Matched, WithdrawMatch{
+				Submitted: sub,
+				Record:    record,
+				Ambiguous: len(owners[keys[i]]) > 1,
+			})
+			break
+		}
+		if !matched {
+			result.Pending = append(result.Pending, sub)
+		}
+	}
+	for i, record := range b.Records {
+		if !used[i] {
+			result.Unexpected = append(result.Unexpected, record)
+		}
+	}
+	return result, nil
+}
+
+// submissionOwner identifies who a withdrawal was submitted for: the job id
+// when known, the player pid otherwise
+func submissionOwner(sub SubmittedWithdrawal) string {
+	if sub.JobID != "" {
+		return "job:" + sub.JobID
+	}
+	return fmt.Sprintf("pid:%v:%v", sub.Pid1, sub.Pid2)
+}
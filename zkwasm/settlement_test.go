@@ -0,0 +1,158 @@
+package zkwasm
+
+import (
+	"errors"
+	"math/big"
+	"reflect"
+	"strings"
+	"testing"
+)
+
+// withdrawRecord lays out one 32-byte settlement record
+func withdrawRecord(t *testing.T, op, index byte, address string, amount [8]byte) []byte {
+	t.Helper()
+	addressBytes, err := ParseAddress(address)
+	if err != nil {
+		t.Fatal(err)
+	}
+	record := []byte{op, index, 0, 0}
+	record = append(record, addressBytes...)
+	return append(record, amount[:]...)
+}
+
+const (
+	settlementAlice = "0x5aAeb6053F3E94C9b9A09f33669435E7Ef1BeAed"
+	settlementBob   = "0xfB6916095ca1df60bB79Ce92cE3Ea74c37c5d359"
+)
+
+func TestDecodeWithdrawRecords(t *testing.T) {
+	txdata := append(
+		withdrawRecord(t, 1, 0, settlementAlice, [8]byte{0, 0, 0, 0, 0, 0, 0x0c, 0x22}),
+		withdrawRecord(t, 1, 1, settlementBob, [8]byte{0, 0, 0, 0, 0, 0, 0x01, 0x00})...,
+	)
+	records, err := DecodeWithdrawRecords(txdata)
+	if err != nil {
+		t.Fatal(err)
+	}
+	// amounts follow bytesToDecimal: 0x0c 0x22 reads "1234" and 0x01 0x00
+	// reads "0100", not the big-endian 3106 and 256
+	want := []WithdrawRecord{
+		{Op: 1, Index: 0, Address: settlementAlice, Amount: big.NewInt(1234)},
+		{Op: 1, Index: 1, Address: settlementBob, Amount: big.NewInt(100)},
+	}
+	if !reflect.DeepEqual(records, want) {
+		t.Fatalf("records = %+v, want %+v", records, want)
+	}
+
+	if records, err := DecodeWithdrawRecords([]byte{0}); err != nil || records != nil {
+		t.Fatalf("single byte = %v, %v, want no records", records, err)
+	}
+	if _, err := DecodeWithdrawRecords(txdata[:40]); !errors.Is(err, ErrInvalidSettlementData) {
+		t.Fatalf("truncated err = %v", err)
+	}
+	overflow := withdrawRecord(t, 1, 0, settlementAlice, [8]byte{0xff, 0xff, 0xff, 0xff, 0xff, 0xff, 0xff, 0xff})
+	if _, err := DecodeWithdrawRecords(overflow); !errors.Is(err, ErrInvalidSettlementData) {
+		t.Fatalf("overflowing amount err = %v", err)
+	}
+}
+
+func TestBytesToDecimal(t *testing.T) {
+	got := bytesToDecimal([]byte{0, 7, 42, 99, 100, 255})
+	if want := "00074299100255"; got != want {
+		t.Fatalf("bytesToDecimal = %s, want %s", got, want)
+	}
+}
+
+func testSettlement(t *testing.T) *SettlementBatch {
+	t.Helper()
+	var txdata []byte
+	for i, r := range []struct {
+		address string
+		amount  byte
+	}{
+		{settlementAlice, 10},
+		{settlementBob, 20},
+		{settlementAlice, 30},
+		{settlementAlice, 10},
+	} {
+		txdata = append(txdata, withdrawRecord(t, 1, byte(i), r.address, [8]byte{7: r.amount})...)
+	}
+	batch, err := InspectSettlement(txdata)
+	if err != nil {
+		t.Fatal(err)
+	}
+	return batch
+}
+
+func TestInspectSettlement(t *testing.T) {
+	batch := testSettlement(t)
+	if got := batch.Addresses(); !reflect.DeepEqual(got, []string{settlementAlice, settlementBob}) {
+		t.Fatalf("addresses = %v", got)
+	}
+	groups := batch.ByAddress()
+	if len(groups[settlementAlice]) != 3 || len(groups[settlementBob]) != 1 {
+		t.Fatalf("groups = %+v", groups)
+	}
+	totals := batch.Totals()
+	if totals[settlementAlice].Int64() != 50 || totals[settlementBob].Int64() != 20 {
+		t.Fatalf("totals = %v", totals)
+	}
+	if batch.Total().Int64() != 70 {
+		t.Fatalf("total = %v", batch.Total())
+	}
+
+	if _, err := InspectSettlement(make([]byte, 33)); !errors.Is(err, ErrInvalidSettlementData) {
+		t.Fatalf("err = %v", err)
+	}
+}
+
+func TestReconcile(t *testing.T) {
+	batch := testSettlement(t)
+	lower := strings.ToLower(settlementBob)
+	submitted := []SubmittedWithdrawal{
+		{Pid1: big.NewInt(1), Pid2: big.NewInt(1), JobID: "a", Address: settlementAlice, Amount: big.NewInt(10)},
+		{Pid1: big.NewInt(2), Pid2: big.NewInt(2), JobID: "b", Address: lower, Amount: big.NewInt(20)},
+		{Pid1: big.NewInt(3), Pid2: big.NewInt(3), JobID: "c", Address: settlementAlice, Amount: big.NewInt(10)},
+		{Pid1: big.NewInt(1), Pid2: big.NewInt(1), JobID: "d", Address: settlementBob, Amount: big.NewInt(40)},
+	}
+	result, err := batch.Reconcile(submitted)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if len(result.Matched) != 3 {
+		t.Fatalf("matched = %+v", result.Matched)
+	}
+	for i, want := range []struct {
+		job       string
+		index     byte
+		ambiguous bool
+	}{{"a", 0, true}, {"b", 1, false}, {"c", 3, true}} {
+		m := result.Matched[i]
+		if m.Submitted.JobID != want.job || m.Record.Index != want.index || m.Ambiguous != want.ambiguous {
+			t.Fatalf("match %d = job %s record %d ambiguous %v, want %+v", i, m.Submitted.JobID, m.Record.Index, m.Ambiguous, want)
+		}
+	}
+	if len(result.Pending) != 1 || result.Pending[0].JobID != "d" {
+		t.Fatalf("pending = %+v", result.Pending)
+	}
+	if len(result.Unexpected) != 1 || result.Unexpected[0].Index != 2 {
+		t.Fatalf("unexpected = %+v", result.Unexpected)
+	}
+}
+
+func TestReconcileErrors(t *testing.T) {
+	batch := testSettlement(t)
+	duplicate := []SubmittedWithdrawal{
+		{JobID: "a", Address: settlementAlice, Amount: big.NewInt(10)},
+		{JobID: "a", Address: settlementAlice, Amount: big.NewInt(30)},
+	}
+	if _, err := batch.Reconcile(duplicate); !errors.Is(err, ErrDuplicateWithdrawal) {
+		t.Fatalf("duplicate job err = %v", err)
+	}
+	if _, err := batch.Reconcile([]SubmittedWithdrawal{{JobID: "a", Address: "0x12", Amount: big.NewInt(10)}}); !errors.Is(err, ErrInvalidAddress) {
+		t.Fatalf("bad address err = %v", err)
+	}
+	if _, err := batch.Reconcile([]SubmittedWithdrawal{{JobID: "a", Address: settlementAlice}}); !errors.Is(err, ErrAmountOutOfRange) {
+		t.Fatalf("missing amount err = %v", err)
+	}
+}
@@ -2,7 +2,6 @@ package zkwasm
 
 import (
 	"encoding/hex"
-	"fmt"
 	"math/big"
 	"strings"
 )
@@ -84,29 +83,51 @@ func (l *LeHexInt) ToU64Array() []*big.Int {
 	return values
 }
 
-// VerifySign verifies a signature
+// VerifySign verifies a signature. The public key and R must be
+// canonical points of the prime-order subgroup other than the identity,
+// and s must be below the subgroup order. Any other input, including
+// off-curve points, is rejected rather than causing a panic.
 func VerifySign(msg *LeHexInt, pkx, pky, rx, ry *LeHexInt, s *LeHexInt) bool {
-	l := PointBase().Mul((*Field)(NewCurveField(s.ToInt()))) // Mul 方法需要指针接收器
-	pkey := &Point{(*Field)(NewCurveField(pkx.ToInt())), (*Field)(NewCurveField(pky.ToInt()))}
-
-	// 将 Point 存储在变量中，避免临时值
-	r := &Point{(*Field)(NewCurveField(rx.ToInt())), (*Field)(NewCurveField(ry.ToInt()))}
-
-	// 使用 r 调用 Add 方法
-	r = r.Add(pkey.Mul((*Field)(NewCurveField(msg.ToInt())))) // Add 方法返回一个新的 Point 指针
-
-	// 直接在 r.x 上调用 Neg 方法
-	negr := &Point{r.x.Neg(), r.y} // r 已经是指针类型，所以可以直接调用
+	sv := s.ToInt()
+	if sv.Cmp(curveFieldArith.modulus) >= 0 {
+		return false
+	}
+	pkey, ok := subgroupPoint(pkx, pky)
+	if !ok {
+		return false
+	}
+	r, ok := subgroupPoint(rx, ry)
+	if !ok {
+		return false
+	}
+	// s*G - H*A - R is the identity for a valid signature
+	h := NewCurveField(msg.ToInt()).Field()
+	sum, err := MultiScalarMul(
+		[]*Point{PointBase(), pkey.Neg(), r.Neg()},
+		[]*Field{NewField(sv), h, NewField(big.NewInt(1))},
+	)
+	return err == nil && sum.IsZero()
+}
 
-	// 最后的 IsZero 检查
-	return l.Add(negr).IsZero() // Add 和 IsZero 都是指针接收器的方法
+// subgroupPoint decodes untrusted coordinates, accepting only a canonical
+// point of the prime-order subgroup other than the identity
+func subgroupPoint(x, y *LeHexInt) (*Point, bool) {
+	xv, yv := x.ToInt(), y.ToInt()
+	if xv.Cmp(fieldArith.modulus) >= 0 || yv.Cmp(fieldArith.modulus) >= 0 {
+		return nil, false
+	}
+	p := NewPoint(NewField(xv), NewField(yv))
+	if p.IsZero() || !p.IsInSubgroup() {
+		return nil, false
+	}
+	return p, true
 }
 
 // Sign signs a command using a private key
 func Sign(cmd [4]*big.Int, prikey string) map[string]string {
 	pkey := PrivateKeyFromString(prikey)
 	r := pkey.R()
-	R := PointBase().Mul((*Field)(r))
+	R := PointBaseMulSecret(r.Field())
 	bigCmd0 := cmd[0] // cmd[0]
 	bigCmd1 := cmd[1] // cmd[1]
 	bigCmd2 := cmd[2] // cmd[2]
@@ -121,17 +142,16 @@ func Sign(cmd [4]*big.Int, prikey string) map[string]string {
 	H := new(big.Int).Add(bigCmd0, shifted1) // cmd[0] + shifted1
 	H.Add(H, shifted2)                       // Add shifted2
 	H.Add(H, shifted3)
-	fmt.Println("H:", H)
 	hbn := NewCurveField(H)
 	S := r.Add(pkey.key.Mul(hbn))
 	pubkey := pkey.PublicKey()
 	data := map[string]string{
-		"msg":  BnToHexLe(hbn.v),
-		"pkx":  BnToHexLe(pubkey.key.x.v),
-		"pky":  BnToHexLe(pubkey.key.y.v),
-		"sigx": BnToHexLe(R.x.v),
-		"sigy": BnToHexLe(R.y.v),
-		"sigr": BnToHexLe(S.v),
+		"msg":  BnToHexLe(hbn.BigInt()),
+		"pkx":  BnToHexLe(pubkey.key.x.BigInt()),
+		"pky":  BnToHexLe(pubkey.key.y.BigInt()),
+		"sigx": BnToHexLe(R.x.BigInt()),
+		"sigy": BnToHexLe(R.y.BigInt()),
+		"sigr": BnToHexLe(S.BigInt()),
 	}
 	return data
 }
@@ -141,17 +161,13 @@ func Query(prikey string) map[string]string {
 	pkey := PrivateKeyFromString(prikey)
 	pubkey := pkey.PublicKey()
 	data := map[string]string{
-		"pkx": BnToHexLe(pubkey.key.x.v),
+		"pkx": BnToHexLe(pubkey.key.x.BigInt()),
 	}
 	return data
 }
 
 // GetPid retrieves the PID associated with a private key
 func GetPid(prikey string) (*big.Int, *big.Int) {
-	pkey := PrivateKeyFromString(prikey)
-	pubkey := pkey.PublicKey()
-	pidKey := BnToHexLe(pubkey.key.x.v)
-	pidAll := (&LeHexInt{pidKey}).ToU64Array()
-	pid1, pid2 := pidAll[1], pidAll[2]
+	pid1, pid2, _ := PidFromPkx(Query(prikey)["pkx"])
 	return pid1, pid2
 }
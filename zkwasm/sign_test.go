@@ -0,0 +1,249 @@
+package zkwasm
+
+import (
+	"bytes"
+	"errors"
+	"io"
+	"math/big"
+	"sync"
+	"testing"
+)
+
+// signature is a VerifySign input decoded from a Sign result
+type signature struct {
+	msg, pkx, pky, rx, ry, s *big.Int
+}
+
+func validSignature() signature {
+	data := Sign(testCommand(1), "1234")
+	return signature{
+		msg: LittleEndianHexToInt(data["msg"]),
+		pkx: LittleEndianHexToInt(data["pkx"]),
+		pky: LittleEndianHexToInt(data["pky"]),
+		rx:  LittleEndianHexToInt(data["sigx"]),
+		ry:  LittleEndianHexToInt(data["sigy"]),
+		s:   LittleEndianHexToInt(data["sigr"]),
+	}
+}
+
+// verify runs VerifySign, failing the test instead of crashing on a panic
+func (sig signature) verify(t *testing.T) (ok bool) {
+	t.Helper()
+	defer func() {
+		if r := recover(); r != nil {
+			t.Fatalf("VerifySign panicked: %v", r)
+		}
+	}()
+	hex := func(v *big.Int) *LeHexInt { return &LeHexInt{HexStr: BnToHexLe(v)} }
+	return VerifySign(hex(sig.msg), hex(sig.pkx), hex(sig.pky), hex(sig.rx), hex(sig.ry), hex(sig.s))
+}
+
+// smallOrderPoints returns the identity and the points of order 2 and 4
+func smallOrderPoints(t *testing.T) []*Point {
+	one := NewField(big.NewInt(1))
+	zero := NewField(big.NewInt(0))
+	points := []*Point{NewPoint(zero, one), NewPoint(zero, one.Neg())}
+	// (x, 0) with a*x^2 = 1
+	x, ok := one.Div(Constants["a"]).Sqrt()
+	if !ok {
+		t.Fatal("1/a is not a square")
+	}
+	points = append(points, NewPoint(x, zero), NewPoint(x.Neg(), zero))
+	for _, p := range points {
+		if !p.IsOnCurve() || !p.Mul(NewField(big.NewInt(4))).IsZero() {
+			t.Fatalf("%v is not a point of order dividing 4", p)
+		}
+	}
+	return points
+}
+
+func TestVerifySignAcceptsValid(t *testing.T) {
+	if !validSignature().verify(t) {
+		t.Fatal("valid signature rejected")
+	}
+}
+
+func TestVerifySignRejectsSmallOrderPoints(t *testing.T) {
+	for _, p := range smallOrderPoints(t) {
+		sig := validSignature()
+		sig.pkx, sig.pky = p.x.BigInt(), p.y.BigInt()
+		if sig.verify(t) {
+			t.Fatalf("accepted public key %v", p)
+		}
+		sig = validSignature()
+		sig.rx, sig.ry = p.x.BigInt(), p.y.BigInt()
+		if sig.verify(t) {
+			t.Fatalf("accepted R %v", p)
+		}
+	}
+
+	// a valid key plus a point of order 2 is on the curve but outside the
+	// subgroup, and a signature for it could be forged
+	sig := validSignature()
+	pk := NewPoint(NewField(sig.pkx), NewField(sig.pky))
+	mixed := pk.Add(smallOrderPoints(t)[1])
+	if !mixed.IsOnCurve() {
+		t.Fatal("mixed-order key is off the curve")
+	}
+	sig.pkx, sig.pky = mixed.x.BigInt(), mixed.y.BigInt()
+	if sig.verify(t) {
+		t.Fatal("accepted a mixed-order public key")
+	}
+}
+
+func TestVerifySignRejectsOffCurvePoints(t *testing.T) {
+	offsets := []*big.Int{big.NewInt(1), big.NewInt(2), new(big.Int).Sub(fieldArith.modulus, big.NewInt(1))}
+	for _, offset := range offsets {
+		sig := validSignature()
+		sig.pky = new(big.Int).Mod(new(big.Int).Add(sig.pky, offset), fieldArith.modulus)
+		if sig.verify(t) {
+			t.Fatalf("accepted a public key with y moved by %s", offset)
+		}
+		sig = validSignature()
+		sig.rx = new(big.Int).Mod(new(big.Int).Add(sig.rx, offset), fieldArith.modulus)
+		if sig.verify(t) {
+			t.Fatalf("accepted an R with x moved by %s", offset)
+		}
+	}
+	// points whose sums hit a zero denominator in the addition law
+	for _, coords := range [][2]int64{{0, 0}, {1, 0}, {1, 1}} {
+		sig := validSignature()
+		sig.pkx, sig.pky = big.NewInt(coords[0]), big.NewInt(coords[1])
+		sig.rx, sig.ry = big.NewInt(coords[0]), big.NewInt(coords[1])
+		if sig.verify(t) {
+			t.Fatalf("accepted off-curve points %v", coords)
+		}
+	}
+}
+
+func TestVerifySignRejectsNonCanonicalEncodings(t *testing.T) {
+	p := fieldArith.modulus
+	fields := map[string]func(*signature) **big.Int{
+		"pkx": func(s *signature) **big.Int { return &s.pkx },
+		"pky": func(s *signature) **big.Int { return &s.pky },
+		"rx":  func(s *signature) **big.Int { return &s.rx },
+		"ry":  func(s *signature) **big.Int { return &s.ry },
+	}
+	for name, field := range fields {
+		sig := validSignature()
+		v := field(&sig)
+		*v = new(big.Int).Add(*v, p)
+		if sig.verify(t) {
+			t.Fatalf("accepted %s + p", name)
+		}
+	}
+}
+
+func TestVerifySignRejectsLargeS(t *testing.T) {
+	q := curveFieldArith.modulus
+	for _, s := range []func(*big.Int) *big.Int{
+		func(s *big.Int) *big.Int { return new(big.Int).Add(s, q) },
+		func(*big.Int) *big.Int { return new(big.Int).Set(q) },
+		func(*big.Int) *big.Int { return new(big.Int).Sub(new(big.Int).Lsh(big.NewInt(1), 256), big.NewInt(1)) },
+	} {
+		sig := validSignature()
+		sig.s = s(sig.s)
+		if sig.verify(t) {
+			t.Fatalf("accepted s = %s", sig.s)
+		}
+	}
+}
+
+func TestInvOfZero(t *testing.T) {
+	zero := NewField(big.NewInt(0))
+	if !zero.Inv().IsZero() || !NewField(big.NewInt(3)).Div(zero).IsZero() {
+		t.Fatal("Field inverse of zero is not zero")
+	}
+	if !NewCurveField(0).Inv().IsZero() || !NewCurveField(3).Div(NewCurveField(0)).IsZero() {
+		t.Fatal("CurveField inverse of zero is not zero")
+	}
+	if inv, ok := NewField(big.NewInt(0)).InvChecked(); ok || inv != nil {
+		t.Fatalf("Field InvChecked of zero = %v, %v", inv, ok)
+	}
+	if inv, ok := NewCurveField(0).InvChecked(); ok || inv != nil {
+		t.Fatalf("CurveField InvChecked of zero = %v, %v", inv, ok)
+	}
+	three := NewField(big.NewInt(3))
+	if inv, ok := three.InvChecked(); !ok || !inv.Mul(three).Equal(NewField(big.NewInt(1))) {
+		t.Fatalf("Field InvChecked of 3 = %v, %v", inv, ok)
+	}
+	if inv, ok := NewCurveField(3).InvChecked(); !ok || !inv.Mul(NewCurveField(3)).Equal(NewCurveField(1)) {
+		t.Fatalf("CurveField InvChecked of 3 = %v, %v", inv, ok)
+	}
+}
+
+func TestOffCurveArithmeticDoesNotPanic(t *testing.T) {
+	defer func() {
+		if r := recover(); r != nil {
+			t.Fatalf("panicked: %v", r)
+		}
+	}()
+	// (0, 0) is off the curve and doubles to Z = 0 in extended coordinates,
+	// which has no affine form rather than giving a bogus (0, 0)
+	p := NewPoint(NewField(big.NewInt(0)), NewField(big.NewInt(0)))
+	if double := p.Double(); double != nil || double.IsOnCurve() || double.IsZero() || double.Equal(p) {
+		t.Fatalf("2 * (0, 0) = %v, want nil", double)
+	}
+	if sum := p.Add(p); sum.IsOnCurve() {
+		t.Fatalf("(0, 0) + (0, 0) = %v, on the curve", sum)
+	}
+	// (1, 1) + (1, 1/d) makes d*T1*T2 = Z1*Z2 and so Z = 0
+	one := NewField(big.NewInt(1))
+	if sum := NewPoint(one, one).Add(NewPoint(one, one.Div(Constants["d"]))); sum != nil {
+		t.Fatalf("(1, 1) + (1, 1/d) = %v, want nil", sum)
+	}
+	if product := p.Mul(NewField(big.NewInt(5))); product != nil {
+		t.Fatalf("5 * (0, 0) = %v, want nil", product)
+	}
+	if product := p.MulSecret(NewField(big.NewInt(5))); product != nil {
+		t.Fatalf("5 * (0, 0) = %v, want nil", product)
+	}
+	if sum, err := MultiScalarMul([]*Point{p}, []*Field{NewField(big.NewInt(16))}); !errors.Is(err, ErrInvalidPoint) {
+		t.Fatalf("MultiScalarMul of (0, 0) = %v, %v, want ErrInvalidPoint", sum, err)
+	}
+	if p.IsInSubgroup() {
+		t.Fatal("(0, 0) is in the subgroup")
+	}
+}
+
+// TestSignNoncesDiffer signs the same command from many goroutines at once,
+// as close to the same nanosecond as a test can get. A nonce seeded from
+// the clock would repeat and leak the key through S1 - S2 = (H1 - H2) * k.
+func TestSignNoncesDiffer(t *testing.T) {
+	const n = 64
+	var wg sync.WaitGroup
+	sigs := make([]map[string]string, n)
+	start := make(chan struct{})
+	for i := range sigs {
+		wg.Add(1)
+		go func(i int) {
+			defer wg.Done()
+			<-start
+			sigs[i] = Sign(testCommand(1), "1234")
+		}(i)
+	}
+	close(start)
+	wg.Wait()
+
+	seen := make(map[string]bool)
+	for _, sig := range sigs {
+		if seen[sig["sigx"]] {
+			t.Fatalf("nonce point %s used twice", sig["sigx"])
+		}
+		seen[sig["sigx"]] = true
+	}
+}
+
+func TestNoncesComeFromRandReader(t *testing.T) {
+	defer func(r io.Reader) { randReader = r }(randReader)
+	randReader = bytes.NewReader(append(make([]byte, 31), 5))
+	if r := RandomPrivateKey().key.BigInt(); r.Int64() != 5 {
+		t.Fatalf("key = %s, want the scalar read from randReader", r)
+	}
+
+	// a zero draw is skipped
+	randReader = bytes.NewReader(append(make([]byte, 32), append(make([]byte, 31), 7)...))
+	if r := PrivateKeyFromString("1234").R().BigInt(); r.Int64() != 7 {
+		t.Fatalf("nonce = %s, want the first non-zero scalar read from randReader", r)
+	}
+}
@@ -0,0 +1,61 @@
+package zkwasm
+
+import (
+	"errors"
+	"fmt"
+	"math/big"
+	"strings"
+)
+
+var ErrInvalidPkx = errors.New("InvalidPkx")
+
+// Signer produces the signed payloads sent to the rollup. KeySigner signs
+// with a local private key; other implementations may delegate to a
+// remote or hardware signer.
+type Signer interface {
+	// Sign returns the /send payload for the transaction limbs
+	Sign(cmd [4]*big.Int) (map[string]string, error)
+	// Pkx returns the little-endian hex x coordinate of the public key,
+	// which identifies the player in /query
+	Pkx() string
+}
+
+// KeySigner signs with a hex private key
+type KeySigner struct {
+	prikey string
+	pkx    string
+}
+
+// NewKeySigner creates a Signer for the hex private key
+func NewKeySigner(prikey string) *KeySigner {
+	return &KeySigner{prikey: prikey, pkx: Query(prikey)["pkx"]}
+}
+
+// Sign signs the transaction limbs
+func (s *KeySigner) Sign(cmd [4]*big.Int) (map[string]string, error) {
+	return Sign(cmd, s.prikey), nil
+}
+
+// Pkx returns the little-endian hex x coordinate of the public key
+func (s *KeySigner) Pkx() string {
+	return s.pkx
+}
+
+// PidFromPkx derives the player id limbs from a little-endian hex pkx, the
+// same way GetPid does from a private key
+func PidFromPkx(pkx string) (*big.Int, *big.Int, error) {
+	hexStr := strings.TrimPrefix(pkx, "0x")
+	if len(hexStr) != 64 {
+		return nil, nil, fmt.Errorf("%w: expected 64 hex characters, got %d", ErrInvalidPkx, len(hexStr))
+	}
+	if _, ok := new(big.Int).SetString(hexStr, 16); !ok {
+		return nil, nil, fmt.Errorf("%w: %s is not hex", ErrInvalidPkx, pkx)
+	}
+	pidAll := (&LeHexInt{hexStr}).ToU64Array()
+	return pidAll[1], pidAll[2], nil
+}
+
+// SignerPid returns the player id limbs of the signer key
+func SignerPid(s Signer) (*big.Int, *big.Int, error) {
+	return PidFromPkx(s.Pkx())
+}
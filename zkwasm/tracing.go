@@ -0,0 +1,203 @@
+package zkwasm
+
+import (
+	"context"
+	"crypto/rand"
+	"encoding/hex"
+	"net/http"
+	"sync"
+	"time"
+)
+
+// Span attribute keys set by the client. Key material and signatures are
+// never recorded.
+const (
+	AttrJobID     = "zkwasm.job_id"
+	AttrCommandID = "zkwasm.command_id"
+	AttrNonce     = "zkwasm.nonce"
+	AttrPoll      = "zkwasm.poll"
+)
+
+// Span is one timed operation of a trace
+type Span interface {
+	SetAttribute(key string, value interface{})
+	RecordError(err error)
+	End()
+	// Traceparent returns the W3C trace context header value of the span,
+	// or "" if it should not be propagated
+	Traceparent() string
+}
+
+// Tracer starts spans. Start must return a context carrying the new span
+// so spans started from it become its children. zkwasmotel adapts an
+// OpenTelemetry tracer.
+type Tracer interface {
+	Start(ctx context.Context, name string) (context.Context, Span)
+}
+
+// WithTracer traces every transaction: a zkwasm.transaction span with one
+// zkwasm.attempt child per send attempt, holding the nonce it was signed
+// with, and zkwasm.sign, zkwasm.send and one zkwasm.poll per job poll under
+// each attempt. Outgoing requests carry the traceparent header of the
+// current span.
+func WithTracer(tracer Tracer) Option {
+	return func(rpc *ZKWasmAppRpc) {
+		rpc.tracer = tracer
+	}
+}
+
+type spanKey struct{}
+
+// ContextWithSpan returns a copy of ctx carrying span
+func ContextWithSpan(ctx context.Context, span Span) context.Context {
+	return context.WithValue(ctx, spanKey{}, span)
+}
+
+// SpanFromContext returns the span carried by ctx, or nil
+func SpanFromContext(ctx context.Context) Span {
+	span, _ := ctx.Value(spanKey{}).(Span)
+	return span
+}
+
+type noopSpan struct{}
+
+func (noopSpan) SetAttribute(string, interface{}) {}
+func (noopSpan) RecordError(error)                {}
+func (noopSpan) End()                             {}
+func (noopSpan) Traceparent() string              { return "" }
+
+// startSpan starts a span when the client has a tracer and a no-op span
+// otherwise, so call sites need no nil checks
+func (rpc *ZKWasmAppRpc) startSpan(ctx context.Context, name string) (context.Context, Span) {
+	if rpc.tracer == nil {
+		return ctx, noopSpan{}
+	}
+	ctx, span := rpc.tracer.Start(ctx, name)
+	return ContextWithSpan(ctx, span), span
+}
+
+// endSpan records err, if any, and ends span
+func endSpan(span Span, err error) {
+	if err != nil {
+		span.RecordError(err)
+	}
+	span.End()
+}
+
+// injectTraceparent propagates the span of the request context
+func injectTraceparent(req *http.Request) {
+	span := SpanFromContext(req.Context())
+	if span == nil {
+		return
+	}
+	if traceparent := span.Traceparent(); traceparent != "" {
+		req.Header.Set("traceparent", traceparent)
+	}
+}
+
+// RecordedSpan is a finished span kept by a SpanRecorder
+type RecordedSpan struct {
+	Name    string
+	TraceID string
+	SpanID  string
+	// ParentID is empty for root spans
+	ParentID   string
+	Attributes map[string]interface{}
+	Errors     []error
+	Start      time.Time
+	End        time.Time
+}
+
+// SpanRecorder is an in-memory Tracer keeping finished spans, for tests and
+// debugging
+type SpanRecorder struct {
+	mu    sync.Mutex
+	spans []RecordedSpan
+}
+
+// NewSpanRecorder creates an empty recorder
+func NewSpanRecorder() *SpanRecorder {
+	return &SpanRecorder{}
+}
+
+func (r *SpanRecorder) Start(ctx context.Context, name string) (context.Context, Span) {
+	span := &recorderSpan{
+		recorder: r,
+		data: RecordedSpan{
+			Name:       name,
+			SpanID:     randomHex(8),
+			Attributes: make(map[string]interface{}),
+			Start:      time.Now(),
+		},
+	}
+	if parent, ok := SpanFromContext(ctx).(*recorderSpan); ok {
+		span.data.TraceID = parent.data.TraceID
+		span.data.ParentID = parent.data.SpanID
+	} else {
+		span.data.TraceID = randomHex(16)
+	}
+	return ContextWithSpan(ctx, span), span
+}
+
+// Spans returns the finished spans in the order they ended
+func (r *SpanRecorder) Spans() []RecordedSpan {
+	r.mu.Lock()
+	defer r.mu.Unlock()
+	return append([]RecordedSpan{}, r.spans...)
+}
+
+// Reset drops the recorded spans
+func (r *SpanRecorder) Reset() {
+	r.mu.Lock()
+	defer r.mu.Unlock()
+	r.spans = nil
+}
+
+type recorderSpan struct {
+	recorder *SpanRecorder
+	mu       sync.Mutex
+	data     RecordedSpan
+	ended    bool
+}
+
+func (s *recorderSpan) SetAttribute(key string, value interface{}) {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	s.data.Attributes[key] = value
+}
+
+func (s *recorderSpan) RecordError(err error) {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	s.data.Errors = append(s.data.Errors, err)
+}
+
+func (s *recorderSpan) End() {
+	s.mu.Lock()
+	if s.ended {
+		s.mu.Unlock()
+		return
+	}
+	s.ended = true
+	s.data.End = time.Now()
+	data := s.data
+	data.Attributes = make(map[string]interface{}, len(s.data.Attributes))
+	for k, v := range s.data.Attributes {
+		data.Attributes[k] = v
+	}
+	s.mu.Unlock()
+
+	s.recorder.mu.Lock()
+	defer s.recorder.mu.Unlock()
+	s.recorder.spans = append(s.recorder.spans, data)
+}
+
+func (s *recorderSpan) Traceparent() string {
+	return "00-" + s.data.TraceID + "-" + s.data.SpanID + "-01"
+}
+
+func randomHex(n int) string {
+	b := make([]byte, n)
+	rand.Read(b)
+	return hex.EncodeToString(b)
+}
@@ -0,0 +1,45 @@
+package zkwasm
+
+import (
+	"context"
+	"testing"
+)
+
+func TestTracingNoncePerAttempt(t *testing.T) {
+	fake, server := newFakeRollup(t)
+	signer := NewKeySigner("1234")
+	fake.nonces[signer.Pkx()] = 5
+	tracer := NewSpanRecorder()
+	rpc := NewZKWasmAppRpc(server.URL, WithRetry(fastRetry), WithTracer(tracer))
+
+	if _, err := rpc.SendTransactionWithSigner(context.Background(), signer, testCommand(2)); err != nil {
+		t.Fatal(err)
+	}
+	var transaction RecordedSpan
+	var attempts []RecordedSpan
+	for _, span := range tracer.Spans() {
+		switch span.Name {
+		case "zkwasm.transaction":
+			transaction = span
+		case "zkwasm.attempt":
+			attempts = append(attempts, span)
+		}
+	}
+	if _, ok := transaction.Attributes[AttrNonce]; ok {
+		t.Fatalf("transaction span records nonce %v, which a re-sign makes stale", transaction.Attributes[AttrNonce])
+	}
+	if len(attempts) != 2 {
+		t.Fatalf("%d attempt spans, want 2", len(attempts))
+	}
+	for i, want := range []uint64{2, 5} {
+		if got := attempts[i].Attributes[AttrNonce]; got != want {
+			t.Fatalf("attempt %d nonce = %v, want %d", i+1, got, want)
+		}
+		if attempts[i].ParentID != transaction.SpanID {
+			t.Fatalf("attempt %d is not a child of the transaction", i+1)
+		}
+	}
+	if len(attempts[0].Errors) != 1 || len(attempts[1].Errors) != 0 {
+		t.Fatalf("attempt errors = %v, %v", attempts[0].Errors, attempts[1].Errors)
+	}
+}
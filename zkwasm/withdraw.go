@@ -0,0 +1,107 @@
+package zkwasm
+
+import (
+	"encoding/hex"
+	"errors"
+	"fmt"
+	"math/big"
+	"strings"
+)
+
+var (
+	ErrInvalidAddress       = errors.New("InvalidAddress")
+	ErrInvalidChecksum      = errors.New("InvalidAddressChecksum")
+	ErrAmountOutOfRange     = errors.New("AmountOutOfRange")
+	ErrInvalidWithdrawLimbs = errors.New("InvalidWithdrawLimbs")
+)
+
+// MaxWithdrawAmount is the largest amount that fits next to the address
+// prefix in the first withdraw limb
+const MaxWithdrawAmount = 0xFFFFFFFF
+
+var (
+	maxWithdrawAmount = new(big.Int).SetUint64(MaxWithdrawAmount)
+	maxU64            = new(big.Int).SetUint64(0xFFFFFFFFFFFFFFFF)
+)
+
+// ParseAddress parses a 20-byte L1 address, with or without a 0x prefix.
+// Mixed-case input must carry a valid EIP-55 checksum; all-lowercase and
+// all-uppercase input is accepted as is.
+func ParseAddress(address string) ([]byte, error) {
+	hexStr := strings.TrimPrefix(strings.TrimPrefix(address, "0x"), "0X")
+	if len(hexStr) != 40 {
+		return nil, fmt.Errorf("%w: expected 40 hex characters, got %d", ErrInvalidAddress, len(hexStr))
+	}
+	addressBytes, err := hex.DecodeString(hexStr)
+	if err != nil {
+		return nil, fmt.Errorf("%w: %v", ErrInvalidAddress, err)
+	}
+	if hexStr != strings.ToLower(hexStr) && hexStr != strings.ToUpper(hexStr) {
+		if ChecksumAddress(addressBytes) != "0x"+hexStr {
+			return nil, fmt.Errorf("%w: %s", ErrInvalidChecksum, address)
+		}
+	}
+	return addressBytes, nil
+}
+
+// ChecksumAddress formats a 20-byte address with its EIP-55 checksum
+func ChecksumAddress(address []byte) string {
+	lower := hex.EncodeToString(address)
+	hash := keccak256([]byte(lower))
+	result := []byte(lower)
+	for i, c := range result {
+		if c < 'a' {
+			continue
+		}
+		nibble := hash[i/2]
+		if i%2 == 0 {
+			nibble >>= 4
+		}
+		if nibble&0x0f >= 8 {
+			result[i] = c - 'a' + 'A'
+		}
+	}
+	return "0x" + string(result)
+}
+
+// ComposeWithdrawParams encodes an L1 address and amount into the three u64
+// limbs expected by the withdraw command: the first four address bytes sit
+// above a 32-bit amount, the remaining sixteen bytes fill the other two limbs.
+func ComposeWithdrawParams(address string, amount *big.Int) ([3]*big.Int, error) {
+	var params [3]*big.Int
+	addressBytes, err := ParseAddress(address)
+	if err != nil {
+		return params, err
+	}
+	if amount == nil || amount.Sign() < 0 || amount.Cmp(maxWithdrawAmount) > 0 {
+		return params, fmt.Errorf("%w: amount must be within [0, %d]", ErrAmountOutOfRange, MaxWithdrawAmount)
+	}
+	firstLimb := new(big.Int).SetBytes(reverseBytes(append([]byte{}, addressBytes[:4]...)))
+	sndLimb := new(big.Int).SetBytes(reverseBytes(append([]byte{}, addressBytes[4:12]...)))
+	thirdLimb := new(big.Int).SetBytes(reverseBytes(append([]byte{}, addressBytes[12:20]...)))
+	params[0] = new(big.Int).Add(new(big.Int).Lsh(firstLimb, 32), amount)
+	params[1] = sndLimb
+	params[2] = thirdLimb
+	return params, nil
+}
+
+// DecomposeWithdrawParams reverses ComposeWithdrawParams, returning the
+// EIP-55 checksummed address and the amount carried by the limbs
+func DecomposeWithdrawParams(params [3]*big.Int) (string, *big.Int, error) {
+	for i, limb := range params {
+		if limb == nil || limb.Sign() < 0 || limb.Cmp(maxU64) > 0 {
+			return "", nil, fmt.Errorf("%w: limb %d is not a u64", ErrInvalidWithdrawLimbs, i)
+		}
+	}
+	amount := new(big.Int).And(params[0], maxWithdrawAmount)
+	firstLimb := new(big.Int).Rsh(params[0], 32)
+
+	addressBytes := make([]byte, 20)
+	firstLimb.FillBytes(addressBytes[:4])
+	params[1].FillBytes(addressBytes[4:12])
+	params[2].FillBytes(addressBytes[12:20])
+	reverseBytes(addressBytes[:4])
+	reverseBytes(addressBytes[4:12])
+	reverseBytes(addressBytes[12:20])
+	return ChecksumAddress(addressBytes), amount, nil
+}
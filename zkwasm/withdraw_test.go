@@ -0,0 +1,130 @@
+package zkwasm
+
+import (
+	"errors"
+	"math/big"
+	"strings"
+	"testing"
+)
+
+// eip55Vectors are the test addresses of the EIP-55 specification
+var eip55Vectors = []string{
+	// all caps
+	"0x52908400098527886E0F7030069857D2E4169EE7",
+	"0x8617E340B3D01FA5F11F306F4090FD50E238070D",
+	// all lower
+	"0xde709f2102306220921060314715629080e2fb77",
+	"0x27b1fdb04752bbc536007a920d24acb045561c26",
+	// normal
+	"0x5aAeb6053F3E94C9b9A09f33669435E7Ef1BeAed",
+	"0xfB6916095ca1df60bB79Ce92cE3Ea74c37c5d359",
+	"0xdbF03B407c01E7cD3CBea99509d93f8DDDC8C6FB",
+	"0xD1220A0cf47c7B9Be7A2E6BA89F429762e7b9aDb",
+}
+
+func TestChecksumAddress(t *testing.T) {
+	for _, want := range eip55Vectors {
+		address, err := ParseAddress(strings.ToLower(want))
+		if err != nil {
+			t.Fatal(err)
+		}
+		if got := ChecksumAddress(address); got != want {
+			t.Errorf("ChecksumAddress(%s) = %s", want, got)
+		}
+		if _, err := ParseAddress(want); err != nil {
+			t.Errorf("ParseAddress(%s): %v", want, err)
+		}
+	}
+}
+
+func TestParseAddressErrors(t *testing.T) {
+	tests := []struct {
+		address string
+		err     error
+	}{
+		{"", ErrInvalidAddress},
+		{"0x5aAeb6053F3E94C9b9A09f33669435E7Ef1BeA", ErrInvalidAddress},
+		{"0x5aAeb6053F3E94C9b9A09f33669435E7Ef1BeAedAA", ErrInvalidAddress},
+		{"0x5aAeb6053F3E94C9b9A09f33669435E7Ef1BeAeg", ErrInvalidAddress},
+		// one letter of a valid checksum flipped in case
+		{"0x5AAeb6053F3E94C9b9A09f33669435E7Ef1BeAed", ErrInvalidChecksum},
+		{"0xfb6916095ca1df60bB79Ce92cE3Ea74c37c5d359", ErrInvalidChecksum},
+	}
+	for _, tt := range tests {
+		if _, err := ParseAddress(tt.address); !errors.Is(err, tt.err) {
+			t.Errorf("ParseAddress(%q) err = %v, want %v", tt.address, err, tt.err)
+		}
+	}
+	if _, err := ParseAddress("5aaeb6053f3e94c9b9a09f33669435e7ef1beaed"); err != nil {
+		t.Errorf("address without prefix: %v", err)
+	}
+}
+
+func TestWithdrawParamsRoundTrip(t *testing.T) {
+	tests := []struct {
+		address string
+		amount  uint64
+	}{
+		{eip55Vectors[4], 0},
+		{eip55Vectors[5], 1},
+		{eip55Vectors[6], 1234567},
+		{eip55Vectors[7], MaxWithdrawAmount},
+		{"0xffffffffffffffffffffffffffffffffffffffff", MaxWithdrawAmount},
+		{"0x0000000000000000000000000000000000000000", 5},
+	}
+	for _, tt := range tests {
+		params, err := ComposeWithdrawParams(tt.address, new(big.Int).SetUint64(tt.amount))
+		if err != nil {
+			t.Fatalf("compose %s %d: %v", tt.address, tt.amount, err)
+		}
+		for i, limb := range params {
+			if limb.Sign() < 0 || limb.Cmp(maxU64) > 0 {
+				t.Fatalf("limb %d = %s is not a u64", i, limb)
+			}
+		}
+		address, amount, err := DecomposeWithdrawParams(params)
+		if err != nil {
+			t.Fatal(err)
+		}
+		want, _ := ParseAddress(tt.address)
+		if address != ChecksumAddress(want) || amount.Uint64() != tt.amount {
+			t.Errorf("round trip of %s %d = %s %s", tt.address, tt.amount, address, amount)
+		}
+	}
+}
+
+func TestWithdrawParamsLayout(t *testing.T) {
+	// the address bytes fill each limb little-endian, the first four above
+	// the 32-bit amount
+	params, err := ComposeWithdrawParams("0x0102030405060708090a0b0c0d0e0f1011121314", big.NewInt(7))
+	if err != nil {
+		t.Fatal(err)
+	}
+	want := []uint64{0x04030201_00000007, 0x0c0b0a0908070605, 0x14131211100f0e0d}
+	for i, limb := range params {
+		if limb.Uint64() != want[i] {
+			t.Errorf("limb %d = %#x, want %#x", i, limb, want[i])
+		}
+	}
+}
+
+func TestWithdrawParamsRangeErrors(t *testing.T) {
+	address := eip55Vectors[4]
+	for _, amount := range []*big.Int{nil, big.NewInt(-1), new(big.Int).SetUint64(MaxWithdrawAmount + 1)} {
+		if _, err := ComposeWithdrawParams(address, amount); !errors.Is(err, ErrAmountOutOfRange) {
+			t.Errorf("amount %v: err = %v, want ErrAmountOutOfRange", amount, err)
+		}
+	}
+	if _, err := ComposeWithdrawParams("0x1234", big.NewInt(1)); !errors.Is(err, ErrInvalidAddress) {
+		t.Errorf("short address: err = %v, want ErrInvalidAddress", err)
+	}
+
+	valid := [3]*big.Int{big.NewInt(1), big.NewInt(2), big.NewInt(3)}
+	for i, bad := range []*big.Int{nil, big.NewInt(-1), new(big.Int).Lsh(big.NewInt(1), 64)} {
+		params := valid
+		params[i] = bad
+		if _, _, err := DecomposeWithdrawParams(params); !errors.Is(err, ErrInvalidWithdrawLimbs) {
+			t.Errorf("limb %d = %v: err = %v, want ErrInvalidWithdrawLimbs", i, bad, err)
+		}
+	}
+}
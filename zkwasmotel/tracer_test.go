@@ -0,0 +1,115 @@
+package zkwasmotel
+
+import (
+	"context"
+	"encoding/json"
+	"math/big"
+	"net/http"
+	"net/http/httptest"
+	"sync"
+	"testing"
+
+	"go.opentelemetry.io/otel/attribute"
+	"go.opentelemetry.io/otel/codes"
+	sdktrace "go.opentelemetry.io/otel/sdk/trace"
+	"go.opentelemetry.io/otel/sdk/trace/tracetest"
+
+	"zkwasm-minirollup-rpc-go/zkwasm"
+)
+
+// rollup accepts every send as job 1 and remembers the traceparent header
+// of each request
+type rollup struct {
+	mu           sync.Mutex
+	traceparents map[string]string
+}
+
+func (r *rollup) ServeHTTP(w http.ResponseWriter, req *http.Request) {
+	r.mu.Lock()
+	r.traceparents[req.URL.Path] = req.Header.Get("traceparent")
+	r.mu.Unlock()
+	w.Header().Set("Content-Type", "application/json")
+	w.WriteHeader(http.StatusCreated)
+	switch req.URL.Path {
+	case "/send":
+		json.NewEncoder(w).Encode(map[string]interface{}{"success": true, "jobid": "1"})
+	default:
+		json.NewEncoder(w).Encode(map[string]interface{}{"finishedOn": 1, "returnvalue": map[string]interface{}{}})
+	}
+}
+
+func TestTracerRecordsTransaction(t *testing.T) {
+	server := &rollup{traceparents: make(map[string]string)}
+	httpServer := httptest.NewServer(server)
+	defer httpServer.Close()
+	recorder := tracetest.NewSpanRecorder()
+	provider := sdktrace.NewTracerProvider(sdktrace.WithSpanProcessor(recorder))
+	rpc := zkwasm.NewZKWasmAppRpc(httpServer.URL, zkwasm.WithTracer(NewTracer(provider.Tracer("zkwasm"))))
+
+	limb, _ := (&zkwasm.Command{Nonce: 3, ID: 1}).Encode()
+	cmd := [4]*big.Int{limb, big.NewInt(0), big.NewInt(0), big.NewInt(0)}
+	if _, err := rpc.SendTransactionWithSigner(context.Background(), zkwasm.NewKeySigner("1234"), cmd); err != nil {
+		t.Fatal(err)
+	}
+
+	spans := make(map[string]sdktrace.ReadOnlySpan)
+	for _, span := range recorder.Ended() {
+		spans[span.Name()] = span
+	}
+	for _, name := range []string{"zkwasm.transaction", "zkwasm.attempt", "zkwasm.sign", "zkwasm.send", "zkwasm.poll"} {
+		if _, ok := spans[name]; !ok {
+			t.Fatalf("no %s span among %d", name, len(recorder.Ended()))
+		}
+	}
+	transaction, attempt, send := spans["zkwasm.transaction"], spans["zkwasm.attempt"], spans["zkwasm.send"]
+	if attempt.Parent().SpanID() != transaction.SpanContext().SpanID() || send.Parent().SpanID() != attempt.SpanContext().SpanID() {
+		t.Fatal("spans are not nested transaction > attempt > send")
+	}
+	if !hasAttribute(attempt.Attributes(), attribute.Int64(zkwasm.AttrNonce, 3)) {
+		t.Fatalf("attempt attributes = %v, want nonce 3", attempt.Attributes())
+	}
+	if !hasAttribute(send.Attributes(), attribute.String(zkwasm.AttrJobID, "1")) {
+		t.Fatalf("send attributes = %v, want job 1", send.Attributes())
+	}
+
+	sc := send.SpanContext()
+	if want := "00-" + sc.TraceID().String() + "-" + sc.SpanID().String() + "-01"; server.traceparents["/send"] != want {
+		t.Fatalf("/send traceparent = %q, want %q", server.traceparents["/send"], want)
+	}
+}
+
+func TestTracerRecordsErrors(t *testing.T) {
+	httpServer := httptest.NewServer(http.NotFoundHandler())
+	defer httpServer.Close()
+	recorder := tracetest.NewSpanRecorder()
+	provider := sdktrace.NewTracerProvider(sdktrace.WithSpanProcessor(recorder))
+	rpc := zkwasm.NewZKWasmAppRpc(httpServer.URL, zkwasm.WithTracer(NewTracer(provider.Tracer("zkwasm"))))
+
+	limb, _ := (&zkwasm.Command{ID: 1}).Encode()
+	cmd := [4]*big.Int{limb, big.NewInt(0), big.NewInt(0), big.NewInt(0)}
+	if _, err := rpc.SendTransactionWithSigner(context.Background(), zkwasm.NewKeySigner("1234"), cmd); err == nil {
+		t.Fatal("send to a missing endpoint succeeded")
+	}
+	failed := 0
+	for _, span := range recorder.Ended() {
+		if span.Name() == "zkwasm.sign" {
+			continue
+		}
+		failed++
+		if span.Status().Code != codes.Error || len(span.Events()) == 0 {
+			t.Fatalf("%s status = %v, events = %v, want a recorded error", span.Name(), span.Status(), span.Events())
+		}
+	}
+	if failed != 3 {
+		t.Fatalf("%d failed spans, want transaction, attempt and send", failed)
+	}
+}
+
+func hasAttribute(attrs []attribute.KeyValue, want attribute.KeyValue) bool {
+	for _, attr := range attrs {
+		if attr == want {
+			return true
+		}
+	}
+	return false
+}
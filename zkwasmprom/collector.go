@@ -0,0 +1,83 @@
+// Package zkwasmprom exports the measurements of a zkwasm.MetricsRecorder
+// through the Prometheus client library. It is a module of its own so only
+// programs importing it depend on client_golang.
+package zkwasmprom
+
+import (
+	"strconv"
+
+	"github.com/prometheus/client_golang/prometheus"
+
+	"zkwasm-minirollup-rpc-go/zkwasm"
+)
+
+var (
+	requestsDesc = prometheus.NewDesc("zkwasm_rpc_requests_total",
+		"HTTP requests sent to the rollup server.", []string{"endpoint", "status"}, nil)
+	requestDurationDesc = prometheus.NewDesc("zkwasm_rpc_request_duration_seconds",
+		"Latency of HTTP requests to the rollup server.", []string{"endpoint"}, nil)
+	jobWaitDesc = prometheus.NewDesc("zkwasm_rpc_job_wait_seconds",
+		"Time spent waiting for transaction jobs.", nil, nil)
+	jobFailuresDesc = prometheus.NewDesc("zkwasm_rpc_job_failures_total",
+		"Transaction jobs that failed, by failure kind.", []string{"reason"}, nil)
+	retriesDesc = prometheus.NewDesc("zkwasm_rpc_retries_total",
+		"Transaction retries by failure kind.", []string{"kind"}, nil)
+	signDurationDesc = prometheus.NewDesc("zkwasm_rpc_sign_duration_seconds",
+		"Time spent signing transactions.", nil, nil)
+	limiterWaitDesc = prometheus.NewDesc("zkwasm_rpc_limiter_wait_seconds",
+		"Time requests waited for the client rate limits.", nil, nil)
+	throttledDesc = prometheus.NewDesc("zkwasm_rpc_throttled_total",
+		"429 responses from the rollup server.", nil, nil)
+)
+
+// Collector is a prometheus.Collector reading a zkwasm.MetricsRecorder
+type Collector struct {
+	recorder *zkwasm.MetricsRecorder
+}
+
+var _ prometheus.Collector = (*Collector)(nil)
+
+// NewCollector creates a Collector for recorder, which is usually also
+// passed to zkwasm.WithMetrics
+func NewCollector(recorder *zkwasm.MetricsRecorder) *Collector {
+	return &Collector{recorder: recorder}
+}
+
+func (c *Collector) Describe(ch chan<- *prometheus.Desc) {
+	ch <- requestsDesc
+	ch <- requestDurationDesc
+	ch <- jobWaitDesc
+	ch <- jobFailuresDesc
+	ch <- retriesDesc
+	ch <- signDurationDesc
+	ch <- limiterWaitDesc
+	ch <- throttledDesc
+}
+
+func (c *Collector) Collect(ch chan<- prometheus.Metric) {
+	s := c.recorder.Snapshot()
+	for k, v := range s.Requests {
+		ch <- prometheus.MustNewConstMetric(requestsDesc, prometheus.CounterValue, float64(v), k.Endpoint, strconv.Itoa(k.Status))
+	}
+	for endpoint, h := range s.RequestLatency {
+		ch <- histogram(requestDurationDesc, h, endpoint)
+	}
+	ch <- histogram(jobWaitDesc, s.JobWait)
+	for reason, v := range s.JobFailures {
+		ch <- prometheus.MustNewConstMetric(jobFailuresDesc, prometheus.CounterValue, float64(v), reason)
+	}
+	for kind, v := range s.Retries {
+		ch <- prometheus.MustNewConstMetric(retriesDesc, prometheus.CounterValue, float64(v), kind)
+	}
+	ch <- histogram(signDurationDesc, s.SignLatency)
+	ch <- histogram(limiterWaitDesc, s.LimiterWait)
+	ch <- prometheus.MustNewConstMetric(throttledDesc, prometheus.CounterValue, float64(s.Throttled))
+}
+
+func histogram(desc *prometheus.Desc, h zkwasm.HistogramSnapshot, labels ...string) prometheus.Metric {
+	buckets := make(map[float64]uint64, len(h.Bounds))
+	for i, bound := range h.Bounds {
+		buckets[bound] = h.Counts[i]
+	}
+	return prometheus.MustNewConstHistogram(desc, h.Count, h.Sum, buckets, labels...)
+}
@@ -0,0 +1,62 @@
+package zkwasmprom
+
+import (
+	"strings"
+	"testing"
+	"time"
+
+	"github.com/prometheus/client_golang/prometheus"
+	"github.com/prometheus/client_golang/prometheus/testutil"
+
+	"zkwasm-minirollup-rpc-go/zkwasm"
+)
+
+func TestCollector(t *testing.T) {
+	recorder := zkwasm.NewMetricsRecorder()
+	recorder.ObserveRequest(zkwasm.EndpointSend, 201, 20*time.Millisecond)
+	recorder.ObserveRequest(zkwasm.EndpointSend, 503, time.Second)
+	recorder.ObserveJob(2*time.Second, &zkwasm.JobFailedError{JobID: "1", Reason: "player not found"})
+	recorder.ObserveRetry(zkwasm.FailureServer)
+	recorder.ObserveThrottled()
+
+	registry := prometheus.NewPedanticRegistry()
+	if err := registry.Register(NewCollector(recorder)); err != nil {
+		t.Fatal(err)
+	}
+	expected := `
+# HELP zkwasm_rpc_requests_total HTTP requests sent to the rollup server.
+# TYPE zkwasm_rpc_requests_total counter
+zkwasm_rpc_requests_total{endpoint="/send",status="201"} 1
+zkwasm_rpc_requests_total{endpoint="/send",status="503"} 1
+# HELP zkwasm_rpc_job_failures_total Transaction jobs that failed, by failure kind.
+# TYPE zkwasm_rpc_job_failures_total counter
+zkwasm_rpc_job_failures_total{reason="application"} 1
+# HELP zkwasm_rpc_retries_total Transaction retries by failure kind.
+# TYPE zkwasm_rpc_retries_total counter
+zkwasm_rpc_retries_total{kind="server"} 1
+# HELP zkwasm_rpc_throttled_total 429 responses from the rollup server.
+# TYPE zkwasm_rpc_throttled_total counter
+zkwasm_rpc_throttled_total 1
+`
+	err := testutil.GatherAndCompare(registry, strings.NewReader(expected),
+		"zkwasm_rpc_requests_total", "zkwasm_rpc_job_failures_total", "zkwasm_rpc_retries_total", "zkwasm_rpc_throttled_total")
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	families, err := registry.Gather()
+	if err != nil {
+		t.Fatal(err)
+	}
+	for _, family := range families {
+		if family.GetName() != "zkwasm_rpc_request_duration_seconds" {
+			continue
+		}
+		h := family.GetMetric()[0].GetHistogram()
+		if h.GetSampleCount() != 2 || h.GetSampleSum() < 1 {
+			t.Fatalf("request duration = %v", h)
+		}
+		return
+	}
+	t.Fatal("request duration histogram missing")
+}